@@ -63,6 +63,11 @@ func loadEnvAndFlags() {
 	pflag.CountP("quiet", "q", "Decrease log verbosity.")
 	pflag.CountP("verbose", "v", "Increase log verbosity.")
 	pflag.StringP("ldappassword-file", "y", "", "Path to LDAP password file.")
+	pflag.BoolP("ldapstart-tls", "Z", false, "Start TLS on ldap:// URI.")
+	pflag.String("record-ldap", k.String("recordldap"), "Path to directory where to save LDAP searches.")
+	pflag.String("replay-ldap", k.String("replayldap"), "Path to directory of LDAP searches to replay instead of searching directory.")
+	pflag.Bool("watch", k.Bool("watch"), "Keep running and synchronize on LDAP changes.")
+	pflag.Duration("watch-interval", time.Hour, "Interval of full synchronization in watch mode.")
 	pflag.Parse()
 
 	// posflag.Provider does not return error.
@@ -94,12 +99,20 @@ type Controller struct {
 	LogLevel       slog.Level
 	Directory      string
 	Dsn            string
+	RecordLdap     string
+	ReplayLdap     string
+	Watch          bool
+	// Interval of periodic synchronization in watch mode.
+	WatchInterval time.Duration
+	// Maximum number of concurrent LDAP searches.
+	LdapConcurrency int `koanf:"ldap_concurrency"`
 }
 
 // Finalize logs the end of ldap2pg execution and determine exit code.
 func (controller Controller) Finalize(errs *errorlist.List, start time.Time, roles, grants, queries int) error {
 	logAttrs := []any{
 		"searches", ldap.Watch.Count,
+		"cachehits", ldap.Watch.Hits,
 		"roles", roles,
 		"queries", queries, // Don't use Watch.Count for dry run case.
 	}
@@ -181,6 +194,9 @@ func unmarshalController() (controller Controller, err error) {
 			slog.Error("Bad verbosity.", "source", "env", "value", verbosity)
 		}
 	}
+	if !k.Exists("ldap_concurrency") {
+		controller.LdapConcurrency = 1
+	}
 	args := pflag.Args()
 	if len(args) > 0 {
 		controller.Dsn = args[0]
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
 	"runtime/pprof"
 	"strings"
+	"syscall"
 	"time"
 
 	"golang.org/x/exp/maps"
@@ -84,13 +87,25 @@ func ldap2pg() (err error) {
 		return
 	}
 
+	if controller.Watch {
+		return watch(ctx, controller, conf)
+	}
+	return synchronize(ctx, controller, conf, start)
+}
+
+// synchronize Postgres instance once with wanted state.
+func synchronize(ctx context.Context, controller Controller, conf config.Config, start time.Time) (err error) {
 	pc := conf.Postgres.Build()
 	// Inspect session, running user, user options, blacklist, etc.
 	instance, err := inspect.Stage0(ctx, pc)
 	if err != nil {
 		return
 	}
-	wantedRoles, wantedGrants, err := conf.Rules.Run(instance.RolesBlacklist)
+	wantedRoles, wantedGrants, err := conf.Rules.Run(instance.RolesBlacklist, controller.LdapConcurrency)
+	if err != nil {
+		return
+	}
+	err = wantedRoles.CheckMembershipOptions(instance.ServerVersionNum)
 	if err != nil {
 		return
 	}
@@ -99,6 +114,10 @@ func ldap2pg() (err error) {
 	if err != nil {
 		return
 	}
+	err = wantedRoles.HashPasswords(instance.AllRoles, instance.ManagedRoles)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
 
 	syncErrors := errorlist.New("synchronization errors")
 
@@ -188,6 +207,58 @@ func ldap2pg() (err error) {
 	)
 }
 
+// Delay between first LDAP change and synchronization. Changes in this delay
+// are synchronized at once.
+var watchDebounce = 10 * time.Second
+
+// watch synchronizes on LDAP changes and periodically until interrupted.
+func watch(ctx context.Context, controller Controller, conf config.Config) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Watching LDAP changes.", "debounce", watchDebounce, "interval", controller.WatchInterval)
+	changes := make(chan string, 1)
+	errs := conf.Rules.Listen(ctx, changes)
+	reconcile := time.NewTicker(controller.WatchInterval)
+	defer reconcile.Stop()
+
+	cycle := func() {
+		ldap.Watch.Reset()
+		inspect.Watch.Reset()
+		postgres.Watch.Reset()
+		err := synchronize(ctx, controller, conf, time.Now())
+		if err != nil {
+			slog.Error("Synchronization failed. Waiting for next change.", "err", err)
+		}
+		// Don't hold Postgres connection between cycles.
+		postgres.CloseConn(ctx)
+	}
+
+	cycle()
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping watch.")
+			return nil
+		case err := <-errs:
+			return fmt.Errorf("watch: %w", err)
+		case dn := <-changes:
+			if debounce == nil {
+				slog.Info("LDAP change detected.", "dn", dn, "delay", watchDebounce)
+				debounce = time.After(watchDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			cycle()
+		case <-reconcile.C:
+			slog.Info("Running periodic synchronization.")
+			debounce = nil
+			cycle()
+		}
+	}
+}
+
 func changeDirectory(directory string) (err error) {
 	if directory == "" {
 		return
@@ -196,6 +267,48 @@ func changeDirectory(directory string) (err error) {
 	return os.Chdir(directory)
 }
 
+// checkWatch rejects flags incompatible with watch mode.
+func checkWatch(controller Controller) error {
+	if !controller.Watch {
+		return nil
+	}
+	if controller.Check {
+		return fmt.Errorf("--watch and --check are mutually exclusive")
+	}
+	if controller.ReplayLdap != "" {
+		return fmt.Errorf("--watch and --replay-ldap are mutually exclusive")
+	}
+	if controller.WatchInterval <= 0 {
+		return fmt.Errorf("bad watch interval: %s", controller.WatchInterval)
+	}
+	return nil
+}
+
+// configureRecording sets directories to record or replay LDAP searches.
+func configureRecording(record, replay string) (err error) {
+	if record != "" && replay != "" {
+		return fmt.Errorf("--record-ldap and --replay-ldap are mutually exclusive")
+	}
+	if record != "" {
+		ldap.RecordDir, err = filepath.Abs(record)
+		if err != nil {
+			return fmt.Errorf("record: %w", err)
+		}
+		slog.Info("Recording LDAP searches.", "path", ldap.RecordDir)
+		err = os.MkdirAll(ldap.RecordDir, 0o700)
+		if err != nil {
+			return fmt.Errorf("record: %w", err)
+		}
+	}
+	if replay != "" {
+		ldap.ReplayDir, err = filepath.Abs(replay)
+		if err != nil {
+			return fmt.Errorf("replay: %w", err)
+		}
+	}
+	return nil
+}
+
 // configure setup process settings from inputs
 //
 // Configures logging, environment, database connexion, etc.
@@ -216,6 +329,17 @@ func configure() (controller Controller, c config.Config, err error) {
 		slog.Warn("Running a prerelease! Use at your own risks!")
 	}
 
+	err = checkWatch(controller)
+	if err != nil {
+		return
+	}
+
+	// Resolve paths before changing directory.
+	err = configureRecording(controller.RecordLdap, controller.ReplayLdap)
+	if err != nil {
+		return
+	}
+
 	err = changeDirectory(controller.Directory)
 	if err != nil {
 		return
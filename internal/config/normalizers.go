@@ -9,6 +9,7 @@ import (
 	"github.com/dalibo/ldap2pg/v6/internal/normalize"
 	"github.com/dalibo/ldap2pg/v6/internal/privileges"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 func NormalizeConfigRoot(yaml any) (config map[string]any, err error) {
@@ -156,10 +157,16 @@ func NormalizeLdapSearch(yaml any) (search map[string]any, err error) {
 	if err != nil {
 		return
 	}
-	err = normalize.SpuriousKeys(search, "base", "filter", "scope", "subsearches", "on_unexpected_dn")
+	err = normalize.SpuriousKeys(search, "base", "filter", "scope", "page_size", "subsearches", "on_unexpected_dn", "ldif", "directory")
 	if err != nil {
 		return
 	}
+	onUnexpectedDN, ok := search["on_unexpected_dn"]
+	if !ok {
+		search["on_unexpected_dn"] = "warn"
+	} else if !slices.Contains([]any{"fail", "warn", "ignore"}, onUnexpectedDN) {
+		return nil, fmt.Errorf("on_unexpected_dn: bad value: %v", onUnexpectedDN)
+	}
 
 	subsearches, ok := search["subsearches"].(map[string]any)
 	if !ok {
@@ -171,8 +178,12 @@ func NormalizeLdapSearch(yaml any) (search map[string]any, err error) {
 		if err != nil {
 			return
 		}
+		transitive, ok := subsearch["transitive"]
+		if ok {
+			subsearch["transitive"] = normalize.Boolean(transitive)
+		}
 		subsearches[attr] = subsearch
-		err = normalize.SpuriousKeys(subsearch, "filter", "scope")
+		err = normalize.SpuriousKeys(subsearch, "filter", "scope", "page_size", "transitive", "batch_size")
 		if err != nil {
 			return
 		}
@@ -69,3 +69,14 @@ func TestNormalizeConfig(t *testing.T) {
 	syncMap := config["rules"].([]any)
 	r.Len(syncMap, 2)
 }
+
+func TestNormalizeLdapSearchOnUnexpectedDN(t *testing.T) {
+	r := require.New(t)
+
+	search, err := config.NormalizeLdapSearch(map[string]any{"base": "dc=acme"})
+	r.Nil(err)
+	r.Equal("warn", search["on_unexpected_dn"])
+
+	_, err = config.NormalizeLdapSearch(map[string]any{"on_unexpected_dn": "pouet"})
+	r.ErrorContains(err, "on_unexpected_dn")
+}
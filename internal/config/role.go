@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dalibo/ldap2pg/v6/internal/normalize"
 	"golang.org/x/exp/maps"
@@ -48,14 +50,75 @@ func NormalizeRoleRule(yaml any) (rule map[string]any, err error) {
 		if err != nil {
 			return nil, fmt.Errorf("options: %w", err)
 		}
+		err = normalizeRolePassword(rule)
+		if err != nil {
+			return
+		}
+		switch v := rule["disabled"].(type) {
+		case bool:
+			rule["disabled"] = strconv.FormatBool(v)
+		case string:
+			rule["disabled"] = normalize.Boolean(v)
+		}
+		if t, ok := rule["valid_until"].(time.Time); ok {
+			// YAML decodes unquoted timestamps.
+			rule["valid_until"] = t.UTC().Format(time.RFC3339)
+		}
 	default:
 		return nil, fmt.Errorf("bad type: %T", yaml)
 	}
 
-	err = normalize.SpuriousKeys(rule, "names", "comment", "parents", "options", "config", "before_create", "after_create")
+	err = normalize.SpuriousKeys(rule, "names", "comment", "parents", "options", "config", "password", "password_policy", "valid_until", "disabled", "key", "before_create", "after_create", "mirror_nesting")
 	return
 }
 
+// normalizeRolePassword normalizes password to a map with either value, file
+// or command key.
+func normalizeRolePassword(rule map[string]any) (err error) {
+	raw, ok := rule["password"]
+	if !ok {
+		if _, ok := rule["password_policy"]; ok {
+			return errors.New("password_policy: missing password")
+		}
+		return nil
+	}
+
+	var password map[string]any
+	switch raw := raw.(type) {
+	case string:
+		password = map[string]any{"value": raw}
+	case map[string]any:
+		password = raw
+	default:
+		return fmt.Errorf("password: bad type: %T", raw)
+	}
+	err = normalize.SpuriousKeys(password, "value", "file", "command")
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	if len(password) != 1 {
+		return errors.New("password: requires one of value, file or command")
+	}
+	for k, v := range password {
+		if s, ok := v.(string); !ok || s == "" {
+			return fmt.Errorf("password: %s: must be a non-empty string", k)
+		}
+	}
+	rule["password"] = password
+
+	policy, ok := rule["password_policy"]
+	if !ok {
+		policy = "create_only"
+	}
+	switch policy {
+	case "create_only", "always":
+	default:
+		return fmt.Errorf("password_policy: must be create_only or always, got %v", policy)
+	}
+	rule["password_policy"] = policy
+	return nil
+}
+
 // Normalize one rule with a list of names to a list of rules with a single
 // name.
 func DuplicateRoleRules(yaml map[string]any) (rules []map[string]any) {
@@ -127,15 +190,12 @@ func NormalizeMemberships(raw any) (memberships []map[string]any, err error) {
 
 func NormalizeMembership(raw any) (value map[string]any, err error) {
 	value = make(map[string]any)
-	// We could add admin, inherit and set to the map
 
 	switch raw := raw.(type) {
 	case string:
 		value["name"] = raw
 	case map[string]any:
-		for k, v := range raw {
-			value[k] = normalize.Boolean(v)
-		}
+		maps.Copy(value, raw)
 	default:
 		return nil, fmt.Errorf("bad type: %T", raw)
 	}
@@ -145,5 +205,27 @@ func NormalizeMembership(raw any) (value map[string]any, err error) {
 	}
 
 	err = normalize.SpuriousKeys(value, "name", "inherit", "set", "admin")
+	if err != nil {
+		return
+	}
+
+	// Options are booleans, whether YAML value is a bool or a string like
+	// yes.
+	for _, k := range []string{"admin", "inherit", "set"} {
+		v, ok := value[k]
+		if !ok {
+			continue
+		}
+		if s, ok := normalize.Boolean(v).(string); ok {
+			v, err = strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+		if _, ok := v.(bool); !ok {
+			return nil, fmt.Errorf("%s: bad type: %T", k, v)
+		}
+		value[k] = v
+	}
 	return
 }
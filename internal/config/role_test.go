@@ -100,3 +100,89 @@ func TestMembership(t *testing.T) {
 	r.Nil(err)
 	r.Equal("owners", membership["name"])
 }
+
+func TestRolePassword(t *testing.T) {
+	r := require.New(t)
+
+	rawYaml := dedent.Dedent(`
+	name: alice
+	password: "{userPassword}"
+	`)
+	var raw any
+	yaml.Unmarshal([]byte(rawYaml), &raw) //nolint:errcheck
+
+	value, err := config.NormalizeRoleRule(raw)
+	r.Nil(err)
+	r.Equal(map[string]any{"value": "{userPassword}"}, value["password"])
+	r.Equal("create_only", value["password_policy"])
+
+	rawYaml = dedent.Dedent(`
+	name: alice
+	password:
+	  command: vault read -field=password secret/{cn}
+	password_policy: always
+	`)
+	yaml.Unmarshal([]byte(rawYaml), &raw) //nolint:errcheck
+
+	value, err = config.NormalizeRoleRule(raw)
+	r.Nil(err)
+	r.Equal(map[string]any{"command": "vault read -field=password secret/{cn}"}, value["password"])
+	r.Equal("always", value["password_policy"])
+
+	for _, bad := range []string{
+		"{name: alice, password: {file: a, command: b}}",
+		"{name: alice, password: {path: a}}",
+		"{name: alice, password: secret, password_policy: never}",
+		"{name: alice, password_policy: always}",
+	} {
+		var raw any
+		yaml.Unmarshal([]byte(bad), &raw) //nolint:errcheck
+		_, err = config.NormalizeRoleRule(raw)
+		r.Error(err, bad)
+	}
+}
+
+func TestRoleValidUntilAndDisabled(t *testing.T) {
+	r := require.New(t)
+
+	rawYaml := dedent.Dedent(`
+	name: alice
+	valid_until: 2025-12-31
+	disabled: yes
+	`)
+	var raw any
+	yaml.Unmarshal([]byte(rawYaml), &raw) //nolint:errcheck
+
+	value, err := config.NormalizeRoleRule(raw)
+	r.Nil(err)
+	r.Equal("2025-12-31T00:00:00Z", value["valid_until"])
+	r.Equal("true", value["disabled"])
+}
+
+func TestMembershipOptions(t *testing.T) {
+	r := require.New(t)
+
+	rawYaml := dedent.Dedent(`
+	name: app_owner
+	inherit: false
+	set: yes
+	`)
+	var raw any
+	yaml.Unmarshal([]byte(rawYaml), &raw) //nolint:errcheck
+
+	membership, err := config.NormalizeMembership(raw)
+	r.Nil(err)
+	r.Equal(false, membership["inherit"])
+	r.Equal(true, membership["set"])
+
+	membership, err = config.NormalizeMembership(map[string]any{"name": "yes", "admin": "false"})
+	r.Nil(err)
+	r.Equal("yes", membership["name"])
+	r.Equal(false, membership["admin"])
+
+	_, err = config.NormalizeMembership(map[string]any{"name": "a", "set": "maybe"})
+	r.ErrorContains(err, "set: ")
+
+	_, err = config.NormalizeMembership(map[string]any{"name": "a", "grant": true})
+	r.Error(err)
+}
@@ -45,13 +45,11 @@ func (c *Config) LoadYaml(root map[string]any) (err error) {
 
 	for i := range c.Rules {
 		item := &c.Rules[i]
-		item.InferAttributes()
-		// states.ComputeWanted is simplified base on the assumption
-		// there is no more than one sub-search. Fail otherwise.
-		if 1 < len(item.LdapSearch.Subsearches) {
-			err = fmt.Errorf("multiple sub-search unsupported")
-			return
+		directory := item.LdapSearch.Directory
+		if _, ok := c.Ldap.Directories[directory]; directory != "" && !ok {
+			return fmt.Errorf("rules[%d]: ldapsearch: directory: %s: unknown LDAP directory", i, directory)
 		}
+		item.InferAttributes()
 		item.ReplaceAttributeAsSubentryField()
 	}
 
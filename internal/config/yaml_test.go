@@ -31,3 +31,50 @@ func TestLoadPrivilege(t *testing.T) {
 	r.Equal("CONNECT", p[0].Type)
 	r.Equal("DATABASE", p[0].On)
 }
+
+func TestLoadBadMethod(t *testing.T) {
+	r := require.New(t)
+
+	rawYaml := dedent.Dedent(`
+	rules:
+	- ldapsearch:
+	    base: ou=users,dc=acme,dc=tld
+	  role: "{mail.split('@')}"
+	`)
+	var value map[string]any
+	yaml.Unmarshal([]byte(rawYaml), &value) //nolint:errcheck
+
+	root, err := config.NormalizeConfigRoot(value)
+	r.Nil(err)
+	c := config.New()
+	err = c.LoadYaml(root)
+	r.ErrorContains(err, "split() takes 2 arguments, got 1")
+}
+
+func TestLoadUnknownDirectory(t *testing.T) {
+	r := require.New(t)
+
+	rawYaml := dedent.Dedent(`
+	ldap:
+	  directories:
+	    contractors:
+	      uri: ldaps://contractors.acme.tld
+	rules:
+	- ldapsearch:
+	    directory: contractors
+	    base: ou=contractors,dc=acme,dc=tld
+	  role: "{cn}"
+	- ldapsearch:
+	    directory: unknown
+	    base: ou=users,dc=acme,dc=tld
+	  role: "{cn}"
+	`)
+	var value map[string]any
+	yaml.Unmarshal([]byte(rawYaml), &value) //nolint:errcheck
+
+	root, err := config.NormalizeConfigRoot(value)
+	r.Nil(err)
+	c := config.New()
+	err = c.LoadYaml(root)
+	r.ErrorContains(err, "rules[1]: ldapsearch: directory: unknown: unknown LDAP directory")
+}
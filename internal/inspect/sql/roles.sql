@@ -4,7 +4,11 @@ WITH me AS (
 ), memberships AS (
   SELECT ms.member AS member,
          p.rolname AS "name",
-         g.rolname AS "grantor"
+         g.rolname AS "grantor",
+         ms.admin_option AS "admin",
+         -- Postgres 16 options, NULL before.
+         (to_jsonb(ms) ->> 'inherit_option')::boolean AS "inherit",
+         (to_jsonb(ms) ->> 'set_option')::boolean AS "set"
     FROM pg_auth_members AS ms
     JOIN pg_roles AS p ON p.oid = ms.roleid
     JOIN pg_roles AS g ON g.oid = ms.grantor
@@ -16,10 +20,15 @@ SELECT rol.rolname,
        -- Postgres 16 allows: json_arrayagg(memberships.* ORDER BY 2 ABSENT ON NULL)::jsonb AS parents,
        -- may return {NULL}, array_remove can't compare json object.
        array_agg(to_json(memberships.*)) AS parents,
-       rol.rolconfig AS config
+       rol.rolconfig AS config,
+       -- Canonical form of role.NormalizeValidUntil.
+       CASE WHEN isfinite(rol.rolvaliduntil)
+            THEN to_char(rol.rolvaliduntil AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+            ELSE COALESCE(rol.rolvaliduntil::text, '')
+       END AS valid_until
   FROM me
        CROSS JOIN pg_catalog.pg_roles AS rol
        LEFT OUTER JOIN memberships ON memberships.member = rol.oid
  WHERE NOT (rol.rolsuper AND NOT me.rolsuper)
- GROUP BY 1, 2, 3, 5
+ GROUP BY 1, 2, 3, 5, 6
  ORDER BY 1
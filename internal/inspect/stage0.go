@@ -22,6 +22,8 @@ type Instance struct {
 	ManagedRoles     role.Map
 	Me               role.Role
 	RolesBlacklist   lists.Blacklist
+	// Like server_version_num, e.g. 160002.
+	ServerVersionNum int
 }
 
 func Stage0(ctx context.Context, pc Config) (instance Instance, err error) {
@@ -77,9 +79,8 @@ func (instance *Instance) InspectSession(ctx context.Context, fallbackOwner stri
 		panic("No data returned.")
 	}
 	var clusterName, serverVersion string
-	var serverVersionNum int
 	err = rows.Scan(
-		&serverVersion, &serverVersionNum,
+		&serverVersion, &instance.ServerVersionNum,
 		&clusterName, &instance.DefaultDatabase,
 		&instance.Me.Name, &instance.Me.Options.Super,
 	)
@@ -90,7 +91,7 @@ func (instance *Instance) InspectSession(ctx context.Context, fallbackOwner stri
 	var msg string
 	if instance.Me.Options.Super {
 		msg = "Running as superuser."
-	} else if serverVersionNum < 160000 {
+	} else if instance.ServerVersionNum < 160000 {
 		slog.Warn("Running as unprivileged user on Postgres 15 and lower.", "version", serverVersion)
 		slog.Warn("Unprivileged user is flawed before Postgres 16.")
 		slog.Warn("Upgrade to Postgres 16 or later, switch to superuser or stick to ldap2pg 6.0.")
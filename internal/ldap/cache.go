@@ -0,0 +1,168 @@
+package ldap
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// searchCache shares results of sub-searches for the duration of a run.
+//
+// Concurrent lookups of the same search wait for the first one instead of
+// searching directory again.
+type searchCache struct {
+	mu       sync.Mutex
+	searches map[searchKey]*cachedSearch
+}
+
+type searchKey struct {
+	base       string
+	scope      Scope
+	filter     string
+	attributes string
+}
+
+func newSearchKey(base string, scope Scope, filter string, attributes []string) searchKey {
+	return searchKey{
+		// Member DN may differ in case or spaces.
+		base:       NormalizeDN(base),
+		scope:      scope,
+		filter:     filter,
+		attributes: strings.Join(attributes, "\x00"),
+	}
+}
+
+type cachedSearch struct {
+	done    chan struct{}
+	entries []*ldap3.Entry
+	err     error
+}
+
+// get returns the cached search for key. owner is true if caller must
+// resolve the search.
+func (c *searchCache) get(key searchKey) (s *cachedSearch, owner bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.searches[key]
+	if ok {
+		return s, false
+	}
+	s = &cachedSearch{done: make(chan struct{})}
+	c.searches[key] = s
+	return s, true
+}
+
+func (s *cachedSearch) resolve(entries []*ldap3.Entry, err error) {
+	s.entries = entries
+	s.err = err
+	close(s.done)
+}
+
+// Subsearch returns entries of sub-search s for each base DN, in bases order.
+//
+// Results are cached for the lifetime of the pool: the same member of many
+// groups is searched once. If s.BatchSize is greater than one, base-scoped
+// searches of uncached DN are batched in a single search filtering on
+// distinguishedName. Batching requires Active Directory or an LDIF file.
+func (p *Pool) Subsearch(bases []string, s Subsearch) (entries []*ldap3.Entry, errs []error) {
+	searches := make([]*cachedSearch, len(bases))
+	var missing []int
+	for i, base := range bases {
+		var owner bool
+		searches[i], owner = p.cache.get(newSearchKey(base, s.Scope, s.Filter, s.Attributes))
+		if owner {
+			missing = append(missing, i)
+		} else {
+			Watch.Hit()
+		}
+	}
+
+	var batchBase string
+	var batch bool
+	if len(missing) > 0 {
+		batchBase, batch = p.batchBase(s)
+	}
+	for len(missing) > 0 {
+		if !batch {
+			i := missing[0]
+			missing = missing[1:]
+			res, err := p.Search(bases[i], s.Scope, s.Filter, s.Attributes, s.PageSize)
+			if err != nil {
+				searches[i].resolve(nil, err)
+			} else {
+				searches[i].resolve(res.Entries, nil)
+			}
+			continue
+		}
+		n := min(s.BatchSize, len(missing))
+		p.searchBatch(batchBase, bases, missing[:n], searches, s)
+		missing = missing[n:]
+	}
+
+	for _, search := range searches {
+		<-search.done
+		if search.err != nil {
+			errs = append(errs, search.err)
+			continue
+		}
+		entries = append(entries, search.entries...)
+	}
+	return
+}
+
+// batchBase returns the base of batched searches. ok is false if s can't be
+// batched.
+func (p *Pool) batchBase(s Subsearch) (base string, ok bool) {
+	if s.BatchSize < 2 || s.Scope != ldap3.ScopeBaseObject {
+		return "", false
+	}
+	c := <-p.clients
+	defer func() { p.clients <- c }()
+	if c.ldif != nil {
+		return "", true
+	}
+	dse, err := c.ReadRootDSE()
+	if err != nil {
+		slog.Debug("Not batching sub-searches.", "err", err)
+		return "", false
+	}
+	if !dse.ActiveDirectory || dse.DefaultNamingContext == "" {
+		slog.Debug("Not batching sub-searches. Directory has no distinguishedName attribute.")
+		return "", false
+	}
+	return dse.DefaultNamingContext, true
+}
+
+// searchBatch resolves searches of bases at indices with a single search.
+//
+// A DN missing from directory has no entry, instead of failing with no such
+// object.
+func (p *Pool) searchBatch(batchBase string, bases []string, indices []int, searches []*cachedSearch, s Subsearch) {
+	var b strings.Builder
+	for _, i := range indices {
+		fmt.Fprintf(&b, "(distinguishedName=%s)", ldap3.EscapeFilter(bases[i]))
+	}
+	filter := fmt.Sprintf("(&%s(|%s))", s.Filter, b.String())
+	res, err := p.Search(batchBase, ldap3.ScopeWholeSubtree, filter, s.Attributes, s.PageSize)
+	if err != nil {
+		for _, i := range indices {
+			searches[i].resolve(nil, err)
+		}
+		return
+	}
+	found := make(map[string]*ldap3.Entry)
+	for _, entry := range res.Entries {
+		found[NormalizeDN(entry.DN)] = entry
+	}
+	for _, i := range indices {
+		entry, ok := found[NormalizeDN(bases[i])]
+		if ok {
+			searches[i].resolve([]*ldap3.Entry{entry}, nil)
+		} else {
+			searches[i].resolve(nil, nil)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ldap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func newLDIFPool(t *testing.T) *Pool {
+	path := filepath.Join(t.TempDir(), "export.ldif")
+	require.Nil(t, os.WriteFile(path, []byte(testLDIF), 0o600))
+	c, err := OpenLDIF(path)
+	require.Nil(t, err)
+	return NewPool(c, c)
+}
+
+func TestSubsearchCache(t *testing.T) {
+	r := require.New(t)
+
+	p := newLDIFPool(t)
+	s := Subsearch{Scope: ldap3.ScopeBaseObject, Filter: "(objectClass=*)", Attributes: []string{"cn"}}
+	bases := []string{"cn=alice,ou=people,dc=acme,dc=tld", "cn=dba,ou=groups,dc=acme,dc=tld"}
+
+	count, hits := Watch.Count, Watch.Hits
+	entries, errs := p.Subsearch(bases, s)
+	r.Empty(errs)
+	r.Len(entries, 2)
+	r.Equal("alice", entries[0].GetAttributeValue("cn"))
+	r.Equal(count+2, Watch.Count)
+	r.Equal(hits, Watch.Hits)
+
+	// Same DN with other case is cached.
+	entries, errs = p.Subsearch([]string{"CN=Alice,ou=people,dc=acme,dc=tld", "cn=bob,ou=people,dc=acme,dc=tld"}, s)
+	r.Len(errs, 1)
+	r.True(ldap3.IsErrorWithCode(errs[0], ldap3.LDAPResultNoSuchObject))
+	r.Len(entries, 1)
+	r.Equal(count+3, Watch.Count)
+	r.Equal(hits+1, Watch.Hits)
+}
+
+func TestSubsearchBatch(t *testing.T) {
+	r := require.New(t)
+
+	p := newLDIFPool(t)
+	s := Subsearch{Scope: ldap3.ScopeBaseObject, Filter: "(objectClass=*)", Attributes: []string{"cn"}, BatchSize: 2}
+	bases := []string{
+		"cn=alice,ou=people,dc=acme,dc=tld",
+		"cn=bob,ou=people,dc=acme,dc=tld",
+		"cn=dba,ou=groups,dc=acme,dc=tld",
+		"cn=alice,ou=people,dc=acme,dc=tld",
+	}
+
+	count, hits := Watch.Count, Watch.Hits
+	entries, errs := p.Subsearch(bases, s)
+	r.Empty(errs)
+	// bob is missing, alice is returned twice.
+	r.Len(entries, 3)
+	r.Equal("alice", entries[0].GetAttributeValue("cn"))
+	r.Equal("dba", entries[1].GetAttributeValue("cn"))
+	r.Equal("alice", entries[2].GetAttributeValue("cn"))
+	r.Equal(count+2, Watch.Count)
+	r.Equal(hits+1, Watch.Hits)
+}
@@ -2,6 +2,7 @@ package ldap
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -12,48 +13,139 @@ import (
 	"github.com/avast/retry-go/v4"
 	"github.com/dalibo/ldap2pg/v6/internal/perf"
 	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/knadh/koanf/v2"
 )
 
 type Client struct {
+	// Name of directory in YAML. Empty for default directory.
+	Directory   string
 	URI         string
 	BindDN      string
 	SaslMech    string
 	SaslAuthCID string
-	Timeout     time.Duration
-	Password    string
-	Conn        *ldap3.Conn
+	SaslAuthzID string
+	StartTLS    bool
+	// Follow continuation references and referrals.
+	Referrals bool
+	Timeout   time.Duration
+	Password  string
+	// Default page size of searches. 0 disables paging.
+	PageSize uint32
+	Conn     *ldap3.Conn
+	// Searches instead of Conn if not nil, e.g. a fake server.
+	searcher searcher
+	// Connects to referred servers instead of connectReferral if not nil.
+	dialReferral func(uri string) (*Client, error)
+	// Shared by copies of client.
+	rootDSE *RootDSE
+	// Offline directory. nil for a live directory.
+	ldif *ldifDirectory
+	// Directory of recorded searches to replay.
+	replay string
+	// Round-robin URIs and TLS configuration for reconnection.
+	uris []string
+	try  int
+	tls  *tls.Config
+	// ldaprc options of directory.
+	options *koanf.Koanf
 }
 
 var Watch perf.StopWatch
 
-func Connect() (client Client, err error) {
-	uri := k.String("URI")
+// searcher is the search part of ldap3.Conn.
+type searcher interface {
+	Search(*ldap3.SearchRequest) (*ldap3.SearchResult, error)
+	SearchWithPaging(*ldap3.SearchRequest, uint32) (*ldap3.SearchResult, error)
+}
+
+// conn returns the connection searching directory. nil if not connected.
+func (c *Client) conn() searcher {
+	if c.searcher != nil {
+		return c.searcher
+	}
+	if c.Conn == nil {
+		return nil
+	}
+	return c.Conn
+}
+
+// Connect to named directory. Empty name is the default directory.
+func Connect(directory string) (client Client, err error) {
+	options, err := directoryOptions(directory)
+	if err != nil {
+		return
+	}
+	client.Directory = directory
+	client.options = options
+	uri := options.String("URI")
+	if ReplayDir != "" {
+		slog.Info("Replaying recorded LDAP searches.", "path", ReplayDir, "directory", directory)
+		client.URI = uri
+		client.replay = ReplayDir
+		client.rootDSE = &RootDSE{}
+		return
+	}
 	uris := strings.Split(uri, " ")
 	if len(uris) == 0 {
 		err = fmt.Errorf("missing URI")
 		return
 	}
+	path, found := strings.CutPrefix(uris[0], "file://")
+	if found {
+		client, err = OpenLDIF(path)
+		client.Directory = directory
+		return
+	}
 
-	t := tls.Config{
-		InsecureSkipVerify: k.String("TLS_REQCERT") != "try",
+	client.uris = uris
+	client.tls, err = NewTLSConfig(options)
+	if err != nil {
+		return
 	}
+	client.StartTLS = boolOption(options, "START_TLS")
+	client.Referrals = boolOption(options, "REFERRALS")
+	client.Timeout = options.Duration("TIMEOUT") * time.Second
+	client.PageSize = uint32(options.Int("PAGE_SIZE"))
+	client.rootDSE = &RootDSE{}
+
+	err = client.dial()
+	if err != nil {
+		return
+	}
+	err = client.bind()
+	if err != nil {
+		return
+	}
+
+	slog.Info("Connected to LDAP directory.", "uri", client.URI, "directory", directory)
+	return
+}
+
+// dial next URI, retrying other URIs on recoverable error.
+func (c *Client) dial() error {
 	d := net.Dialer{
-		Timeout: k.Duration("NETWORK_TIMEOUT") * time.Second,
+		Timeout: c.options.Duration("NETWORK_TIMEOUT") * time.Second,
 	}
-	try := 0
-	err = retry.Do(
-		func() error {
+	var conn *ldap3.Conn
+	err := retry.Do(
+		func() (err error) {
 			// Round-robin URIs
-			i := try % len(uris)
-			try++
-			client.URI = uris[i]
-			slog.Debug("LDAP dial.", "uri", client.URI, "try", try)
-			client.Conn, err = ldap3.DialURL(
-				client.URI,
-				ldap3.DialWithTLSConfig(&t),
+			i := c.try % len(c.uris)
+			c.try++
+			c.URI = c.uris[i]
+			slog.Debug("LDAP dial.", "uri", c.URI, "try", c.try)
+			conn, err = ldap3.DialURL(
+				c.URI,
+				ldap3.DialWithTLSConfig(c.tls),
 				ldap3.DialWithDialer(&d),
 			)
-			return err
+			if err != nil {
+				return err
+			}
+			if c.StartTLS && strings.HasPrefix(c.URI, "ldap://") {
+				return c.startTLS(conn, c.tls)
+			}
+			return nil
 		},
 		retry.RetryIf(IsErrorRecoverable),
 		retry.OnRetry(LogRetryError),
@@ -61,78 +153,286 @@ func Connect() (client Client, err error) {
 		retry.LastErrorOnly(true),
 	)
 	if err != nil {
-		return
+		return err
 	}
 
-	client.Timeout = k.Duration("TIMEOUT") * time.Second
-	slog.Debug("LDAP set timeout.", "timeout", client.Timeout)
-	client.Conn.SetTimeout(client.Timeout)
+	// Keep previous connection until dial succeeds.
+	if c.Conn != nil {
+		_ = c.Conn.Close()
+	}
+	c.Conn = conn
+	slog.Debug("LDAP set timeout.", "timeout", c.Timeout)
+	c.Conn.SetTimeout(c.Timeout)
+	return nil
+}
 
-	client.SaslMech = k.String("SASL_MECH")
-	switch client.SaslMech {
+// bind reads credentials from ldaprc and authenticates connection. bind runs
+// PASSWORD_COMMAND on each call to get a fresh secret on reconnection.
+func (c *Client) bind() (err error) {
+	c.SaslMech = c.options.String("SASL_MECH")
+	switch c.SaslMech {
 	case "":
-		client.BindDN = k.String("BINDDN")
-		if client.BindDN == "" {
-			err = fmt.Errorf("missing BINDDN")
-			return
+		c.BindDN = c.options.String("BINDDN")
+		if c.BindDN == "" {
+			return fmt.Errorf("missing BINDDN")
 		}
-		password := k.String("PASSWORD")
-		client.Password = "*******"
-		slog.Debug("LDAP simple bind.", "binddn", client.BindDN)
-		err = client.Conn.Bind(client.BindDN, password)
+		var password string
+		password, err = readPassword(c.options)
+		if err != nil {
+			return err
+		}
+		c.Password = "*******"
+		slog.Debug("LDAP simple bind.", "binddn", c.BindDN)
+		err = c.Conn.Bind(c.BindDN, password)
 	case "DIGEST-MD5":
-		client.SaslAuthCID = k.String("SASL_AUTHCID")
-		password := k.String("PASSWORD")
+		c.SaslAuthCID = c.options.String("SASL_AUTHCID")
+		var password string
+		password, err = readPassword(c.options)
+		if err != nil {
+			return err
+		}
+		c.Password = "*******"
 		var parsedURI *url.URL
-		parsedURI, err = url.Parse(client.URI)
+		parsedURI, err = url.Parse(c.URI)
 		if err != nil {
-			return client, err
+			return err
 		}
-		slog.Debug("LDAP SASL/DIGEST-MD5 bind.", "authcid", client.SaslAuthCID, "host", parsedURI.Host)
-		err = client.Conn.MD5Bind(parsedURI.Host, client.SaslAuthCID, password)
+		slog.Debug("LDAP SASL/DIGEST-MD5 bind.", "authcid", c.SaslAuthCID, "host", parsedURI.Host)
+		err = c.Conn.MD5Bind(parsedURI.Host, c.SaslAuthCID, password)
+	case "EXTERNAL":
+		err = c.externalBind()
+	case "GSSAPI":
+		c.SaslAuthCID = c.options.String("SASL_AUTHCID")
+		c.SaslAuthzID = c.options.String("SASL_AUTHZID")
+		err = c.gssapiBind()
 	default:
 		err = fmt.Errorf("unhandled SASL_MECH")
 	}
+	return
+}
+
+// reconnect dials next URI, replacing connection, and binds again.
+func (c *Client) reconnect() error {
+	err := c.dial()
 	if err != nil {
-		return
+		return err
+	}
+	err = c.bind()
+	if err != nil {
+		return err
+	}
+	slog.Info("Reconnected to LDAP directory.", "uri", c.URI)
+	return nil
+}
+
+// Close connection to directory, if any.
+func (c Client) Close() error {
+	if c.Conn == nil {
+		return nil
 	}
+	return c.Conn.Close()
+}
 
-	slog.Info("Connected to LDAP directory.", "uri", client.URI)
-	return
+func (c *Client) startTLS(conn *ldap3.Conn, t *tls.Config) error {
+	t, err := configureTLSForURI(t, c.URI)
+	if err != nil {
+		return err
+	}
+	slog.Debug("LDAP start TLS.", "uri", c.URI)
+	err = conn.StartTLS(t)
+	if err != nil {
+		_ = conn.Close()
+		// Retrying don't fix TLS negociation.
+		return retry.Unrecoverable(fmt.Errorf("start TLS: %w", err))
+	}
+	return nil
+}
+
+// Search directory. pageSize enables RFC 2696 paged results. A result
+// truncated by server size limit is an error, because ldap2pg must not drop
+// roles missing from a partial result.
+//
+// Search saves result in RecordDir if set.
+func (c *Client) Search(base string, scope Scope, filter string, attributes []string, pageSize uint32) (*ldap3.SearchResult, error) {
+	if c.replay != "" {
+		var err error
+		var res *ldap3.SearchResult
+		Watch.TimeIt(func() {
+			res, err = replaySearch(c.replay, c.Directory, base, scope, filter, attributes)
+		})
+		return res, err
+	}
+	res, err := c.search(base, scope, filter, attributes, pageSize)
+	if RecordDir != "" {
+		recordErr := recordSearch(RecordDir, c.Directory, base, scope, filter, attributes, res, err)
+		if recordErr != nil {
+			return nil, fmt.Errorf("record: %w", recordErr)
+		}
+	}
+	return res, err
+}
+
+// search retries transient failures, reconnecting to next URI before each
+// retry. Returns a TransientError if search still fails. search follows
+// referrals if REFERRALS is on.
+func (c *Client) search(base string, scope Scope, filter string, attributes []string, pageSize uint32) (*ldap3.SearchResult, error) {
+	if c.ldif != nil {
+		return c.searchLDIF(base, scope, filter, attributes)
+	}
+	var res *ldap3.SearchResult
+	reconnect := false
+	err := retry.Do(
+		func() (err error) {
+			if reconnect {
+				err = c.reconnect()
+				if err != nil {
+					// Reconnect again on next attempt.
+					return TransientError{Err: fmt.Errorf("reconnect: %w", err)}
+				}
+				reconnect = false
+			}
+			res, err = c.searchDirectory(base, scope, filter, attributes, pageSize)
+			reconnect = IsErrorTransient(err)
+			return
+		},
+		retry.RetryIf(IsErrorTransient),
+		retry.OnRetry(func(n uint, err error) {
+			slog.Warn("Retrying LDAP search.", "err", err, "attempt", n+1)
+		}),
+		retry.Attempts(SearchAttempts),
+		retry.Delay(time.Second),
+		retry.MaxDelay(30*time.Second),
+		retry.LastErrorOnly(true),
+	)
+	var transient TransientError
+	if errors.As(err, &transient) {
+		return nil, transient
+	}
+	if IsErrorTransient(err) {
+		return nil, TransientError{Err: err}
+	}
+	if c.Referrals {
+		return c.followReferrals(scope, filter, attributes, pageSize, res, err)
+	}
+	return res, err
 }
 
-func (c *Client) Search(base string, scope Scope, filter string, attributes []string) (*ldap3.SearchResult, error) {
+func (c *Client) searchDirectory(base string, scope Scope, filter string, attributes []string, pageSize uint32) (*ldap3.SearchResult, error) {
 	search := ldap3.SearchRequest{
 		BaseDN:     base,
 		Scope:      int(scope),
 		Filter:     filter,
 		Attributes: attributes,
 	}
-	args := []string{"-b", search.BaseDN, "-s", scope.String(), search.Filter}
+	args := []string{"-b", search.BaseDN, "-s", scope.String()}
+	if pageSize > 0 {
+		args = append(args, "-E", fmt.Sprintf("pr=%d/noprompt", pageSize))
+	}
+	args = append(args, search.Filter)
 	args = append(args, search.Attributes...)
 	slog.Debug("Searching LDAP directory.", "cmd", c.Command("ldapsearch", args...))
+	conn := c.conn()
+	if conn == nil {
+		// Reconnection failed.
+		return nil, ldap3.NewError(ldap3.ErrorNetwork, errors.New("ldap: not connected"))
+	}
 	var err error
 	var res *ldap3.SearchResult
 	duration := Watch.TimeIt(func() {
-		res, err = c.Conn.Search(&search)
+		if pageSize > 0 {
+			res, err = conn.SearchWithPaging(&search, pageSize)
+		} else {
+			res, err = conn.Search(&search)
+		}
 	})
+	if ldap3.IsErrorWithCode(err, ldap3.LDAPResultSizeLimitExceeded) {
+		slog.Debug("LDAP search truncated.", "duration", duration, "entries", len(res.Entries), "err", err)
+		return nil, fmt.Errorf("search truncated by server size limit, configure paging: %w", err)
+	}
 	if err != nil {
 		slog.Debug("LDAP search failed.", "duration", duration, "err", err)
 		return nil, err
 	}
 	slog.Debug("LDAP search done.", "duration", duration, "entries", len(res.Entries))
+	err = c.expandRanges(res.Entries)
+	if err != nil {
+		return nil, fmt.Errorf("ranged attribute: %w", err)
+	}
 	return res, nil
 }
 
+func (c *Client) searchLDIF(base string, scope Scope, filter string, attributes []string) (*ldap3.SearchResult, error) {
+	slog.Debug("Searching LDIF file.", "path", c.ldif.path, "base", base, "scope", scope, "filter", filter, "attributes", attributes)
+	var err error
+	var res *ldap3.SearchResult
+	Watch.TimeIt(func() {
+		res, err = c.ldif.search(base, scope, filter, attributes)
+	})
+	if err != nil {
+		slog.Debug("LDIF search failed.", "err", err)
+		return nil, err
+	}
+	slog.Debug("LDIF search done.", "entries", len(res.Entries))
+	return res, nil
+}
+
+// ResolvePageSize returns the page size of a search, falling back to PAGE_SIZE
+// from ldaprc if search does not define one.
+func (c Client) ResolvePageSize(pageSize *uint32) uint32 {
+	if pageSize == nil {
+		return c.PageSize
+	}
+	return *pageSize
+}
+
+// SearchAttempts is the maximum number of tries of a search failing with a
+// transient error.
+var SearchAttempts uint = 4
+
+// TransientError is a search error that may succeed later, like a busy
+// server or a network failure.
+type TransientError struct {
+	Err error
+}
+
+func (e TransientError) Error() string {
+	return fmt.Sprintf("transient: %s", e.Err)
+}
+
+func (e TransientError) Unwrap() error {
+	return e.Err
+}
+
+// IsErrorTransient returns true if retrying search may succeed.
+//
 // Implements retry.RetryIfFunc
-func IsErrorRecoverable(err error) bool {
-	ldapErr, ok := err.(*ldap3.Error)
-	if !ok {
+func IsErrorTransient(err error) bool {
+	var transient TransientError
+	if errors.As(err, &transient) {
 		return true
 	}
-	_, ok = ldapErr.Err.(*tls.CertificateVerificationError)
+	var ldapErr *ldap3.Error
+	if errors.As(err, &ldapErr) {
+		switch ldapErr.ResultCode {
+		case ldap3.LDAPResultBusy,
+			ldap3.LDAPResultUnavailable,
+			ldap3.LDAPResultServerDown,
+			ldap3.LDAPResultTimeout,
+			ldap3.LDAPResultConnectError,
+			ldap3.ErrorNetwork:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Implements retry.RetryIfFunc
+func IsErrorRecoverable(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
 	// Retrying don't fix bad certificate
-	return !ok
+	return !errors.As(err, &verifyErr)
 }
 
 // Implements retry.OnRetryFunc
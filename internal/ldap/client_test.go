@@ -0,0 +1,33 @@
+package ldap_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/dalibo/ldap2pg/v6/internal/ldap"
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+func (suite *Suite) TestErrorTransient() {
+	r := suite.Require()
+
+	r.True(ldap.IsErrorTransient(ldap3.NewError(ldap3.LDAPResultBusy, errors.New("busy"))))
+	r.True(ldap.IsErrorTransient(ldap3.NewError(ldap3.LDAPResultUnavailable, errors.New("unavailable"))))
+	r.True(ldap.IsErrorTransient(ldap3.NewError(ldap3.ErrorNetwork, errors.New("ldap: connection closed"))))
+	r.True(ldap.IsErrorTransient(&net.OpError{Op: "read", Err: errors.New("reset")}))
+	wrapped := fmt.Errorf("ranged attribute: %w", ldap3.NewError(ldap3.LDAPResultBusy, errors.New("busy")))
+	r.True(ldap.IsErrorTransient(wrapped))
+
+	r.False(ldap.IsErrorTransient(ldap3.NewError(ldap3.LDAPResultNoSuchObject, errors.New("missing"))))
+	r.False(ldap.IsErrorTransient(ldap3.NewError(ldap3.LDAPResultInvalidCredentials, errors.New("bad password"))))
+	r.False(ldap.IsErrorTransient(errors.New("bad filter")))
+
+	err := fmt.Errorf("step: %w", ldap.TransientError{Err: wrapped})
+	var transient ldap.TransientError
+	r.True(errors.As(err, &transient))
+	r.True(ldap.IsErrorTransient(err))
+	r.Contains(err.Error(), "transient: ranged attribute")
+	// A failed reconnection is retried whatever its cause.
+	r.True(ldap.IsErrorTransient(ldap.TransientError{Err: errors.New("reconnect: bad password")}))
+}
@@ -10,6 +10,9 @@ func (c Client) Command(name string, args ...string) string {
 	if c.URI != "" {
 		cmd = append(cmd, "-H", c.URI)
 	}
+	if c.StartTLS && strings.HasPrefix(c.URI, "ldap://") {
+		cmd = append(cmd, "-ZZ")
+	}
 	if c.Timeout != 0 && name == "ldapsearch" {
 		cmd = append(cmd, "-l", fmt.Sprintf("%.0f", c.Timeout.Seconds()))
 	}
@@ -24,6 +27,9 @@ func (c Client) Command(name string, args ...string) string {
 	if c.SaslAuthCID != "" {
 		cmd = append(cmd, "-U", c.SaslAuthCID)
 	}
+	if c.SaslAuthzID != "" {
+		cmd = append(cmd, "-X", c.SaslAuthzID)
+	}
 	if c.Password != "" {
 		cmd = append(cmd, "-w", "$LDAPPASSWORD")
 	}
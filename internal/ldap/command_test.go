@@ -28,3 +28,44 @@ func (suite *Suite) TestQuote() {
 	r.Equal(`'(cn='"'"toto"'"')'`, ldap.ShellQuote(`(cn='toto')`))
 	r.Equal(`'"'"'"'"'`, ldap.ShellQuote(`"'"`))
 }
+
+func (suite *Suite) TestCommandStartTLS() {
+	r := suite.Require()
+
+	c := ldap.Client{
+		URI:      "ldap://pouet",
+		StartTLS: true,
+	}
+	cmd := c.Command("ldapsearch", "(filter=*)")
+	r.Equal(`ldapsearch -H ldap://pouet -ZZ -x '(filter=*)'`, cmd)
+}
+
+func (suite *Suite) TestCommandSASL() {
+	r := suite.Require()
+
+	c := ldap.Client{
+		URI:      "ldaps://pouet",
+		SaslMech: "EXTERNAL",
+	}
+	r.Equal(`ldapsearch -H ldaps://pouet -Y EXTERNAL '(filter=*)'`, c.Command("ldapsearch", "(filter=*)"))
+
+	c = ldap.Client{
+		URI:         "ldap://pouet",
+		SaslMech:    "GSSAPI",
+		SaslAuthzID: "dn:cn=ldap2pg",
+	}
+	r.Equal(`ldapsearch -H ldap://pouet -Y GSSAPI -X dn:cn=ldap2pg '(filter=*)'`, c.Command("ldapsearch", "(filter=*)"))
+}
+
+func (suite *Suite) TestCommandPasswordPlaceholder() {
+	r := suite.Require()
+
+	c := ldap.Client{
+		URI:      "ldap://pouet",
+		BindDN:   "cn=admin",
+		Password: "*******",
+	}
+	cmd := c.Command("ldapsearch", "(filter=*)")
+	r.Equal(`ldapsearch -H ldap://pouet -D cn=admin -x -w $LDAPPASSWORD '(filter=*)'`, cmd)
+	r.NotContains(cmd, "*******")
+}
@@ -6,8 +6,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/dalibo/ldap2pg/v6/internal/normalize"
+	"github.com/dalibo/ldap2pg/v6/internal/secret"
 	"github.com/knadh/koanf/providers/confmap"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/posflag"
@@ -19,6 +22,8 @@ var KnownRDNs = []string{"cn", "l", "st", "o", "ou", "c", "street", "dc", "uid"}
 
 type Config struct {
 	KnownRDNs []string `mapstructure:"known_rdns"`
+	// Named directories, with ldaprc options as lowercase keys.
+	Directories map[string]map[string]any `mapstructure:"directories"`
 }
 
 func (c Config) apply() {
@@ -28,24 +33,39 @@ func (c Config) apply() {
 	}
 }
 
+// Options of default directory, from ldaprc and environment.
 var k = koanf.New(".")
 
+// Options of named directories, from YAML.
+var directories = make(map[string]*koanf.Koanf)
+
+var defaults = map[string]any{
+	"URI":             "ldap://localhost",
+	"NETWORK_TIMEOUT": "30",
+	"RC":              "ldaprc",
+	"TLS_REQCERT":     "try",
+	"TIMEOUT":         "30",
+}
+
 // cf. https://git.openldap.org/openldap/openldap/-/blob/bf01750381726db3052d94514eec4048c90a616a/libraries/libldap/init.c#L640
 func Initialize(conf Config) error {
 	conf.apply()
+	directories = make(map[string]*koanf.Koanf)
+	for name, options := range conf.Directories {
+		d, err := loadDirectory(options)
+		if err != nil {
+			return fmt.Errorf("directory %s: %w", name, err)
+		}
+		directories[name] = d
+	}
+
 	_, ok := os.LookupEnv("LDAPNOINIT")
 	if ok {
 		slog.Debug("Skip LDAP initialization.")
 		return nil
 	}
 
-	_ = k.Load(confmap.Provider(map[string]any{
-		"URI":             "ldap://localhost",
-		"NETWORK_TIMEOUT": "30",
-		"RC":              "ldaprc",
-		"TLS_REQCERT":     "try",
-		"TIMEOUT":         "30",
-	}, k.Delim()), nil)
+	_ = k.Load(confmap.Provider(defaults, k.Delim()), nil)
 
 	_ = k.Load(env.Provider("LDAP", k.Delim(), func(key string) string {
 		slog.Debug("Loading LDAP environment var.", "var", key)
@@ -64,15 +84,9 @@ func Initialize(conf Config) error {
 		return key, posflag.FlagVal(pflag.CommandLine, f)
 	}), nil)
 
-	passwordFilePath := k.String("PASSWORD_FILE")
-	if passwordFilePath != "" {
-		slog.Debug("Reading password from file.", "path", passwordFilePath)
-		data, err := readSecretFromFile(passwordFilePath)
-		if err != nil {
-			return fmt.Errorf("ldap password: %w", err)
-		}
-		// Set() only throws error when using StrictMerge which is not the case.
-		_ = k.Set("PASSWORD", data)
+	err := loadPasswordFile(k)
+	if err != nil {
+		return err
 	}
 
 	// cf. https://git.openldap.org/openldap/openldap/-/blob/bf01750381726db3052d94514eec4048c90a616a/libraries/libldap/init.c#L741
@@ -101,6 +115,68 @@ func Initialize(conf Config) error {
 	return nil
 }
 
+// loadDirectory reads options of a named directory. Keys are ldaprc options
+// in lowercase, e.g. uri or binddn.
+func loadDirectory(options map[string]any) (*koanf.Koanf, error) {
+	d := koanf.New(".")
+	_ = d.Load(confmap.Provider(defaults, d.Delim()), nil)
+	upper := make(map[string]any)
+	for key, value := range options {
+		upper[strings.ToUpper(key)] = value
+	}
+	_ = d.Load(confmap.Provider(upper, d.Delim()), nil)
+	return d, loadPasswordFile(d)
+}
+
+func loadPasswordFile(options *koanf.Koanf) error {
+	passwordFilePath := options.String("PASSWORD_FILE")
+	if passwordFilePath == "" {
+		return nil
+	}
+	slog.Debug("Reading password from file.", "path", passwordFilePath)
+	data, err := readSecretFromFile(passwordFilePath)
+	if err != nil {
+		return fmt.Errorf("ldap password: %w", err)
+	}
+	// Set() only throws error when using StrictMerge which is not the case.
+	_ = options.Set("PASSWORD", data)
+	return nil
+}
+
+// readPassword returns LDAP password. PASSWORD_COMMAND prevails over PASSWORD
+// and PASSWORD_FILE.
+func readPassword(options *koanf.Koanf) (string, error) {
+	command := options.String("PASSWORD_COMMAND")
+	if command == "" {
+		return options.String("PASSWORD"), nil
+	}
+	password, err := secret.Run(command)
+	if err != nil {
+		return "", fmt.Errorf("ldap password command: %w", err)
+	}
+	return password, nil
+}
+
+// directoryOptions returns options of named directory. Empty name is the
+// default directory configured by ldaprc and environment.
+func directoryOptions(name string) (*koanf.Koanf, error) {
+	if name == "" {
+		return k, nil
+	}
+	options, ok := directories[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown LDAP directory", name)
+	}
+	return options, nil
+}
+
+// boolOption reads a boolean option accepting ldap.conf(5) values like on or
+// yes.
+func boolOption(options *koanf.Koanf, key string) bool {
+	v, _ := strconv.ParseBool(normalize.Boolean(options.String(key)).(string))
+	return v
+}
+
 // readSecretFromFile reads a file and returns its content.
 // It returns an error if the file does not exist or has too open permissions.
 func readSecretFromFile(path string) (string, error) {
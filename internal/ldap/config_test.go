@@ -0,0 +1,41 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPassword(t *testing.T) {
+	r := require.New(t)
+
+	options := koanf.New(".")
+	_ = options.Set("PASSWORD", "fromrc")
+	password, err := readPassword(options)
+	r.NoError(err)
+	r.Equal("fromrc", password)
+
+	_ = options.Set("PASSWORD_COMMAND", "echo fromcommand")
+	password, err = readPassword(options)
+	r.NoError(err)
+	r.Equal("fromcommand", password)
+
+	_ = options.Set("PASSWORD_COMMAND", "false")
+	_, err = readPassword(options)
+	r.ErrorContains(err, "ldap password command: false")
+}
+
+func TestGSSAPIBindAuthCID(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("KRB5_CLIENT_KTNAME", "FILE:/nonexistent.keytab")
+	options := koanf.New(".")
+	_ = options.Set("SASL_MECH", "GSSAPI")
+	_ = options.Set("SASL_AUTHCID", "ldap2pg")
+	c := Client{URI: "ldap://pouet", options: options}
+	// Fails before contacting KDC or directory.
+	r.ErrorContains(c.bind(), "must be a Kerberos principal")
+	r.Equal("ldap2pg", c.SaslAuthCID)
+	r.Contains(c.Command("ldapsearch"), "-Y GSSAPI -U ldap2pg")
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/dalibo/ldap2pg/v6/internal/lists"
 	"github.com/dalibo/ldap2pg/v6/internal/pyfmt"
 	ldap3 "github.com/go-ldap/ldap/v3"
-	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
 
@@ -17,64 +16,119 @@ import (
 type Result struct {
 	// Is nil for static generation
 	Entry *ldap3.Entry
-	// Is empty if no sub-search.
-	SubsearchAttribute string
-	SubsearchEntries   []*ldap3.Entry
+	// Sub-search entries indexed by sub-search attribute. Is empty if no
+	// sub-search.
+	SubsearchEntries map[string][]*ldap3.Entry
+	// Policy for DN without expected RDN: fail, warn or ignore.
+	OnUnexpectedDN string
 }
 
-func (r *Result) GenerateValues(fmts ...pyfmt.Format) <-chan map[string]string {
-	expressions := pyfmt.ListExpressions(fmts...)
+// GenerateValues generates values of fmts for each combination of values of
+// result.
+//
+// on_unexpected_dn policy applies on expressions of fmts only. Generates
+// nothing if entry DN is unexpected.
+func (r *Result) GenerateValues(fmts ...pyfmt.Format) (<-chan map[string]string, error) {
+	return r.GenerateOptionalValues(fmts, nil)
+}
+
+// GenerateOptionalValues generates values of fmts and optional formats like
+// GenerateValues.
+//
+// A missing attribute referenced only by optional formats has an empty value
+// instead of generating nothing, e.g. an entry without password still
+// generates a role.
+func (r *Result) GenerateOptionalValues(fmts, optional []pyfmt.Format) (<-chan map[string]string, error) {
+	required := pyfmt.ListExpressions(fmts...)
+	optionalExpressions := pyfmt.ListExpressions(optional...)
+	expressions := append(slices.Clone(required), optionalExpressions...)
 	attributes := pyfmt.ListVariables(expressions...)
+	checked, keep, err := r.CheckUnexpectedDN(expressions)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan map[string]string)
+	if !keep {
+		close(ch)
+		return ch, nil
+	}
+	r = &checked
 
 	// If sub-search, we want to combine parent attributes with all
 	// combinations of sub-entries at once. We prepare sub-entries
 	// combination and index them by a string key to combine keys with
 	// parent values, all string lists.
 	//
-	// subMap["subentry0-comb0"] = {"cn": "toto"}
-	var subMap map[string]map[string]string
-	if r.SubsearchAttribute != "" {
-		subMap = r.GenerateSubsearchValues(expressions)
+	// subMaps["member"]["subentry0-comb0"] = {"cn": "toto"}
+	//
+	// Each sub-search attribute is resolved independently. The cartesian
+	// product combines keys of all sub-search attributes.
+	subMaps := make(map[string]map[string]map[string]string)
+	subKeys := make(map[string][]string)
+	for attr := range r.SubsearchEntries {
+		subMaps[attr], subKeys[attr] = r.GenerateSubsearchValues(attr, required, optionalExpressions)
 	}
 
-	ch := make(chan map[string]string)
 	go func() {
 		defer close(ch)
-		for values := range r.GenerateCombinations(attributes, maps.Keys(subMap)) {
-			ch <- r.ResolveExpressions(expressions, values, subMap)
+		for values := range r.GenerateCombinations(attributes, subKeys, optionalVariables(required, optionalExpressions)) {
+			ch <- r.ResolveExpressions(expressions, values, subMaps)
 		}
 	}()
-	return ch
+	return ch, nil
 }
 
 // Return a list of expression -> values for formatting, indexed by a string key.
-func (r *Result) GenerateSubsearchValues(parentExpressions []string) map[string]map[string]string {
-	prefix := r.SubsearchAttribute + "."
+// keys lists keys in sub-entries order. Attributes referenced only by
+// optionalExpressions may be missing from sub-entries.
+func (r *Result) GenerateSubsearchValues(subsearchAttr string, parentExpressions, optionalExpressions []string) (subMap map[string]map[string]string, keys []string) {
+	prefix := subsearchAttr + "."
 	// First, remove sub-attribute from parent expressions. For example :
 	// {member.sAMAccountName} become {sAMAccountname} in the scope of the
 	// sub-entry.
-	var expressions []string
-	for _, e := range parentExpressions {
-		if strings.HasPrefix(e, prefix) {
-			expressions = append(expressions, strings.TrimPrefix(e, prefix))
+	trim := func(parentExpressions []string) (expressions []string) {
+		for _, e := range parentExpressions {
+			if strings.HasPrefix(e, prefix) {
+				expressions = append(expressions, strings.TrimPrefix(e, prefix))
+			}
 		}
+		return
 	}
+	required := trim(parentExpressions)
+	optional := trim(optionalExpressions)
+	expressions := append(slices.Clone(required), optional...)
 	subAttributes := pyfmt.ListVariables(expressions...)
-	subMap := make(map[string]map[string]string)
-	for i, subEntry := range r.SubsearchEntries {
+	optionalAttributes := optionalVariables(required, optional)
+	subMap = make(map[string]map[string]string)
+	for i, subEntry := range r.SubsearchEntries[subsearchAttr] {
 		j := 0
 		subResult := Result{Entry: subEntry}
-		for values := range subResult.GenerateCombinations(subAttributes, nil) {
+		for values := range subResult.GenerateCombinations(subAttributes, nil, optionalAttributes) {
 			subKey := fmt.Sprintf("subentry%d-comb%d", i, j)
 			values = subResult.ResolveExpressions(expressions, values, nil)
 			subMap[subKey] = values
+			keys = append(keys, subKey)
 			j++
 		}
 	}
-	return subMap
+	return
+}
+
+// optionalVariables returns variables referenced by optional expressions and
+// not by required expressions.
+func optionalVariables(required, optional []string) (out []string) {
+	requiredVariables := pyfmt.ListVariables(required...)
+	for _, v := range pyfmt.ListVariables(optional...) {
+		if !slices.ContainsFunc(requiredVariables, func(r string) bool { return strings.EqualFold(r, v) }) {
+			out = append(out, v)
+		}
+	}
+	return
 }
 
-func (r *Result) GenerateCombinations(attributes, subKeys []string) <-chan map[string]string {
+// GenerateCombinations generates the cartesian product of values of
+// attributes. A missing optional attribute has an empty value.
+func (r *Result) GenerateCombinations(attributes []string, subKeys map[string][]string, optional []string) <-chan map[string]string {
 	// Extract raw LDAP attributes values from entry.
 	valuesList := make([][]string, len(attributes))
 	for i, attr := range attributes {
@@ -87,11 +141,16 @@ func (r *Result) GenerateCombinations(attributes, subKeys []string) <-chan map[s
 				slog.Warn("Failed to read value from DN.", "dn", r.Entry.DN, "rdn", attr, "err", err)
 			}
 			valuesList[i] = []string{value0}
-		} else if strings.EqualFold(r.SubsearchAttribute, attr) {
-			valuesList[i] = subKeys
+		} else if subsearchAttr := r.subsearchAttribute(attr); subsearchAttr != "" {
+			valuesList[i] = subKeys[subsearchAttr]
 		} else {
+			// Values hold raw bytes of binary attributes like objectGUID.
+			// Format methods like guid() decode them.
 			valuesList[i] = r.Entry.GetEqualFoldAttributeValues(attr)
 		}
+		if len(valuesList[i]) == 0 && slices.Contains(optional, attr) {
+			valuesList[i] = []string{""}
+		}
 	}
 
 	ch := make(chan map[string]string)
@@ -112,7 +171,7 @@ func (r *Result) GenerateCombinations(attributes, subKeys []string) <-chan map[s
 }
 
 // Resolve format expression from entry or pre-resolved expression for sub-entries.
-func (r *Result) ResolveExpressions(expressions []string, attrValues map[string]string, subExprMap map[string]map[string]string) map[string]string {
+func (r *Result) ResolveExpressions(expressions []string, attrValues map[string]string, subExprMaps map[string]map[string]map[string]string) map[string]string {
 	exprMap := make(map[string]string)
 	for _, expr := range expressions {
 		attr, field, hasField := strings.Cut(expr, ".")
@@ -123,13 +182,17 @@ func (r *Result) ResolveExpressions(expressions []string, attrValues map[string]
 		}
 
 		// Case {member.sAMAccountName}
-		if attr == r.SubsearchAttribute {
+		if subExprMap, ok := subExprMaps[attr]; ok {
 			exprMap[expr] = subExprMap[attrValues[attr]][field]
 			continue
 		}
 
 		// Case {member.cn}
 		dn := attrValues[attr]
+		if dn == "" {
+			// Missing optional attribute.
+			continue
+		}
 		value0, err := ResolveFirstRDN(dn, field)
 		if err != nil {
 			slog.Warn("Failed to resolve expression.", "attribute", attr, "dn", dn, "rdn", field, "err", err)
@@ -140,6 +203,17 @@ func (r *Result) ResolveExpressions(expressions []string, attrValues map[string]
 	return exprMap
 }
 
+// subsearchAttribute returns the sub-search attribute matching attr, ignoring
+// case. Returns empty string if attr is not a sub-search attribute.
+func (r *Result) subsearchAttribute(attr string) string {
+	for subsearchAttr := range r.SubsearchEntries {
+		if strings.EqualFold(subsearchAttr, attr) {
+			return subsearchAttr
+		}
+	}
+	return ""
+}
+
 func ResolveFirstRDN(rawDN, relativeField string) (string, error) {
 	dn, err := ldap3.ParseDN(rawDN)
 	if err != nil {
@@ -156,3 +230,12 @@ func ResolveFirstRDN(rawDN, relativeField string) (string, error) {
 
 	return "", fmt.Errorf("no such RDN in DN")
 }
+
+// NormalizeDN returns a canonical form of DN for comparison.
+func NormalizeDN(rawDN string) string {
+	dn, err := ldap3.ParseDN(rawDN)
+	if err != nil {
+		return strings.ToLower(rawDN)
+	}
+	return strings.ToLower(dn.String())
+}
@@ -1,9 +1,12 @@
 package ldap_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/dalibo/ldap2pg/v6/internal/ldap"
+	"github.com/dalibo/ldap2pg/v6/internal/pyfmt"
+	ldap3 "github.com/go-ldap/ldap/v3"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,3 +22,141 @@ func TestResolveRDNUpper(t *testing.T) {
 	exprMap := result.ResolveExpressions(expressions, attrValues, nil)
 	r.Equal("Alice", exprMap["member.cn"])
 }
+
+func TestUnexpectedDN(t *testing.T) {
+	r := require.New(t)
+
+	result := &ldap.Result{
+		Entry: ldap3.NewEntry("cn=group,ou=groups,dc=acme,dc=tld", map[string][]string{
+			"member": {
+				"cn=alice,ou=users,dc=acme,dc=tld",
+				"ou=unexpected,dc=acme,dc=tld",
+			},
+		}),
+		OnUnexpectedDN: "warn",
+	}
+	checked, keep, err := result.CheckUnexpectedDN([]string{"member.cn"})
+	r.Nil(err)
+	r.True(keep)
+	r.Equal([]string{"cn=alice,ou=users,dc=acme,dc=tld"}, checked.Entry.GetAttributeValues("member"))
+	// Result is untouched.
+	r.Len(result.Entry.GetAttributeValues("member"), 2)
+
+	result.OnUnexpectedDN = "fail"
+	_, _, err = result.CheckUnexpectedDN([]string{"member.cn"})
+	r.ErrorContains(err, "unexpected DN")
+	// Other expressions don't fail.
+	_, keep, err = result.CheckUnexpectedDN([]string{"member.ou"})
+	r.Nil(err)
+	r.True(keep)
+
+	result.OnUnexpectedDN = "ignore"
+	_, keep, err = result.CheckUnexpectedDN([]string{"uid"})
+	r.Nil(err)
+	r.False(keep)
+}
+
+func TestGenerateUnexpectedDN(t *testing.T) {
+	r := require.New(t)
+
+	result := &ldap.Result{
+		Entry: ldap3.NewEntry("cn=group,ou=groups,dc=acme,dc=tld", map[string][]string{
+			"member": {
+				"cn=alice,ou=users,dc=acme,dc=tld",
+				"uid=bob,ou=users,dc=acme,dc=tld",
+			},
+		}),
+		OnUnexpectedDN: "ignore",
+	}
+	generate := func(format string) (out []string) {
+		f, err := pyfmt.Parse(format)
+		r.Nil(err)
+		vchan, err := result.GenerateValues(f)
+		r.Nil(err)
+		for values := range vchan {
+			out = append(out, f.Format(values))
+		}
+		return
+	}
+	// Each format drops its own unexpected DN.
+	r.Equal([]string{"bob"}, generate("{member.uid}"))
+	r.Equal([]string{"alice"}, generate("{member.cn}"))
+	r.Nil(generate("{uid}"))
+}
+
+func TestGenerateMultipleSubsearches(t *testing.T) {
+	r := require.New(t)
+
+	result := &ldap.Result{
+		Entry: ldap3.NewEntry("cn=group,ou=groups,dc=acme,dc=tld", nil),
+		SubsearchEntries: map[string][]*ldap3.Entry{
+			"member": {
+				ldap3.NewEntry("cn=alice,ou=users,dc=acme,dc=tld", map[string][]string{"sAMAccountName": {"alice"}}),
+				ldap3.NewEntry("cn=bob,ou=users,dc=acme,dc=tld", map[string][]string{"sAMAccountName": {"bob"}}),
+			},
+			"manager": {
+				ldap3.NewEntry("cn=carol,ou=users,dc=acme,dc=tld", map[string][]string{"mail": {"carol@acme.tld"}}),
+			},
+		},
+	}
+	f, err := pyfmt.Parse("{member.sAMAccountName}:{manager.mail}")
+	r.Nil(err)
+
+	vchan, err := result.GenerateValues(f)
+	r.Nil(err)
+	var names []string
+	for values := range vchan {
+		names = append(names, f.Format(values))
+	}
+	r.Equal([]string{"alice:carol@acme.tld", "bob:carol@acme.tld"}, names)
+}
+
+func TestGenerateSubsearchOrder(t *testing.T) {
+	r := require.New(t)
+
+	var members []*ldap3.Entry
+	var want []string
+	for i := range 12 {
+		name := fmt.Sprintf("user%d", i)
+		members = append(members, ldap3.NewEntry("cn="+name+",ou=users,dc=acme,dc=tld", map[string][]string{"sAMAccountName": {name}}))
+		want = append(want, name)
+	}
+	result := &ldap.Result{
+		Entry:            ldap3.NewEntry("cn=group,ou=groups,dc=acme,dc=tld", nil),
+		SubsearchEntries: map[string][]*ldap3.Entry{"member": members},
+	}
+	f, err := pyfmt.Parse("{member.sAMAccountName}")
+	r.Nil(err)
+	vchan, err := result.GenerateValues(f)
+	r.Nil(err)
+	var names []string
+	for values := range vchan {
+		names = append(names, f.Format(values))
+	}
+	// Sub-entries order, not subentry10 before subentry2.
+	r.Equal(want, names)
+}
+
+func TestGenerateBinaryAttribute(t *testing.T) {
+	r := require.New(t)
+
+	raw := []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x12, 0x00, 0x00, 0x00}
+	entry := &ldap3.Entry{
+		DN: "cn=system,dc=acme,dc=tld",
+		Attributes: []*ldap3.EntryAttribute{{
+			Name:       "objectSid",
+			Values:     []string{string(raw)},
+			ByteValues: [][]byte{raw},
+		}},
+	}
+	result := &ldap.Result{Entry: entry}
+	f, err := pyfmt.Parse("{objectSid.sid()}")
+	r.Nil(err)
+	vchan, err := result.GenerateValues(f)
+	r.Nil(err)
+	var out []string
+	for values := range vchan {
+		out = append(out, f.Format(values))
+	}
+	r.Equal([]string{"S-1-5-18"}, out)
+}
@@ -0,0 +1,260 @@
+// Implements an offline directory backed by an LDIF file.
+//
+// cf. https://www.rfc-editor.org/rfc/rfc2849
+package ldap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// ldifDirectory holds entries of an LDIF file and evaluates searches locally.
+type ldifDirectory struct {
+	path    string
+	entries []*ldap3.Entry
+	// Parsed DN of entries, same order.
+	dns []*ldap3.DN
+}
+
+// OpenLDIF returns a client searching entries of LDIF file at path instead of
+// a live directory.
+func OpenLDIF(path string) (client Client, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return client, fmt.Errorf("ldif: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	d, err := readLDIF(f)
+	if err != nil {
+		return client, fmt.Errorf("ldif: %s: %w", path, err)
+	}
+	d.path = path
+	client.URI = "file://" + path
+	client.ldif = d
+	// An LDIF file has no root DSE.
+	client.rootDSE = &RootDSE{read: true}
+	slog.Info("Loaded LDIF file.", "path", path, "entries", len(d.entries))
+	return client, nil
+}
+
+func readLDIF(r io.Reader) (*ldifDirectory, error) {
+	d := &ldifDirectory{}
+	records, err := splitLDIF(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		entry, err := parseLDIFRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		dn, err := ldap3.ParseDN(entry.DN)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.DN, err)
+		}
+		d.entries = append(d.entries, entry)
+		d.dns = append(d.dns, dn)
+	}
+	return d, nil
+}
+
+// splitLDIF unfolds lines and groups them by record, skipping comments.
+func splitLDIF(r io.Reader) (records [][]string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var record []string
+	comment := false
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(line, " ") {
+			if comment {
+				continue
+			}
+			if len(record) == 0 {
+				return nil, errors.New("unexpected continuation line")
+			}
+			record[len(record)-1] += line[1:]
+			continue
+		}
+		comment = strings.HasPrefix(line, "#")
+		if comment {
+			continue
+		}
+		if line == "" {
+			if len(record) > 0 {
+				records = append(records, record)
+				record = nil
+			}
+			continue
+		}
+		record = append(record, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(record) > 0 {
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseLDIFRecord returns the entry of a content record. Returns nil for
+// version-spec.
+func parseLDIFRecord(lines []string) (*ldap3.Entry, error) {
+	entry := &ldap3.Entry{}
+	for _, line := range lines {
+		name, value, err := parseLDIFLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if entry.DN == "" {
+			switch strings.ToLower(name) {
+			case "version":
+				continue
+			case "dn":
+				entry.DN = value
+				continue
+			default:
+				return nil, fmt.Errorf("%s: expected dn", line)
+			}
+		}
+		if strings.EqualFold(name, "changetype") {
+			return nil, fmt.Errorf("%s: change records are not supported", entry.DN)
+		}
+		attribute := findAttribute(entry, name)
+		if attribute == nil {
+			attribute = &ldap3.EntryAttribute{Name: name}
+			entry.Attributes = append(entry.Attributes, attribute)
+		}
+		attribute.Values = append(attribute.Values, value)
+		attribute.ByteValues = append(attribute.ByteValues, []byte(value))
+	}
+	if entry.DN == "" {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func parseLDIFLine(line string) (name, value string, err error) {
+	name, value, found := strings.Cut(line, ":")
+	if !found {
+		return "", "", fmt.Errorf("%s: missing colon", line)
+	}
+	switch {
+	case strings.HasPrefix(value, ":"):
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimLeft(value[1:], " "))
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", name, err)
+		}
+		value = string(raw)
+	case strings.HasPrefix(value, "<"):
+		return "", "", fmt.Errorf("%s: URL values are not supported", name)
+	default:
+		value = strings.TrimLeft(value, " ")
+	}
+	return name, value, nil
+}
+
+func findAttribute(entry *ldap3.Entry, name string) *ldap3.EntryAttribute {
+	for _, attribute := range entry.Attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return attribute
+		}
+	}
+	return nil
+}
+
+// search evaluates base, scope and filter against entries of LDIF file.
+func (d *ldifDirectory) search(base string, scope Scope, filter string, attributes []string) (*ldap3.SearchResult, error) {
+	packet, err := ldap3.CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var baseDN *ldap3.DN
+	if base != "" {
+		baseDN, err = ldap3.ParseDN(base)
+		if err != nil {
+			return nil, ldap3.NewError(ldap3.LDAPResultInvalidDNSyntax, err)
+		}
+	}
+
+	res := &ldap3.SearchResult{}
+	found := baseDN == nil
+	for i, entry := range d.entries {
+		dn := d.dns[i]
+		if baseDN != nil {
+			if dn.EqualFold(baseDN) {
+				found = true
+			}
+			if !inScope(dn, baseDN, scope) {
+				continue
+			}
+		}
+		match, err := matchFilter(packet, entry)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			res.Entries = append(res.Entries, selectAttributes(entry, attributes))
+		}
+	}
+	if !found {
+		return nil, ldap3.NewError(ldap3.LDAPResultNoSuchObject, fmt.Errorf("%s: no such object in %s", base, d.path))
+	}
+	return res, nil
+}
+
+func inScope(dn, base *ldap3.DN, scope Scope) bool {
+	switch scope {
+	case ldap3.ScopeBaseObject:
+		return dn.EqualFold(base)
+	case ldap3.ScopeSingleLevel:
+		if len(dn.RDNs) == 0 {
+			return false
+		}
+		parent := &ldap3.DN{RDNs: dn.RDNs[1:]}
+		return parent.EqualFold(base)
+	default:
+		return dn.EqualFold(base) || base.AncestorOfFold(dn)
+	}
+}
+
+// selectAttributes returns a copy of entry with only requested attributes.
+func selectAttributes(entry *ldap3.Entry, attributes []string) *ldap3.Entry {
+	out := &ldap3.Entry{DN: entry.DN}
+	for _, attribute := range entry.Attributes {
+		if !wantAttribute(attribute.Name, attributes) {
+			continue
+		}
+		out.Attributes = append(out.Attributes, &ldap3.EntryAttribute{
+			Name:       attribute.Name,
+			Values:     append([]string(nil), attribute.Values...),
+			ByteValues: append([][]byte(nil), attribute.ByteValues...),
+		})
+	}
+	return out
+}
+
+func wantAttribute(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, attribute := range attributes {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+	return false
+}
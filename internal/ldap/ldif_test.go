@@ -0,0 +1,92 @@
+package ldap
+
+import (
+	"strings"
+	"testing"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+var testLDIF = `version: 1
+
+# Organization
+dn: dc=acme,dc=tld
+objectClass: dcObject
+dc: acme
+
+dn: ou=groups,dc=acme,dc=tld
+ou: groups
+
+dn: cn=dba,ou=groups,dc=acme,dc=tld
+objectClass: groupOfNames
+cn: dba
+member: cn=alice,ou=people,dc=acme,dc=tld
+member: CN=Bob, ou=people,dc=acme,dc=tld
+description:: w6lxdWlwZQ==
+
+dn: cn=readers,ou=groups,dc=acme,dc=tld
+objectClass: groupOfNames
+cn: read
+ ers
+uidNumber: 1500
+
+dn: ou=people,dc=acme,dc=tld
+ou: people
+
+dn: cn=alice,ou=people,dc=acme,dc=tld
+objectClass: person
+cn: alice
+sn: Alice
+`
+
+func TestReadLDIF(t *testing.T) {
+	r := require.New(t)
+
+	d, err := readLDIF(strings.NewReader(testLDIF))
+	r.Nil(err)
+	r.Len(d.entries, 6)
+	dba := d.entries[2]
+	r.Equal("cn=dba,ou=groups,dc=acme,dc=tld", dba.DN)
+	r.Len(dba.GetAttributeValues("member"), 2)
+	r.Equal("équipe", dba.GetAttributeValue("description"))
+	r.Equal("readers", d.entries[3].GetAttributeValue("cn"))
+
+	_, err = readLDIF(strings.NewReader("dn: cn=toto\nchangetype: delete\n"))
+	r.ErrorContains(err, "change records")
+	_, err = readLDIF(strings.NewReader("cn: toto\n"))
+	r.ErrorContains(err, "expected dn")
+}
+
+func TestSearchLDIF(t *testing.T) {
+	r := require.New(t)
+
+	d, err := readLDIF(strings.NewReader(testLDIF))
+	r.Nil(err)
+
+	res, err := d.search("ou=groups,dc=acme,dc=tld", ldap3.ScopeWholeSubtree, "(objectClass=groupOfNames)", []string{"cn"})
+	r.Nil(err)
+	r.Len(res.Entries, 2)
+	r.Len(res.Entries[0].Attributes, 1)
+	r.Equal("dba", res.Entries[0].GetAttributeValue("cn"))
+
+	res, err = d.search("DC=acme, dc=tld", ldap3.ScopeSingleLevel, "(objectClass=*)", nil)
+	r.Nil(err)
+	r.Len(res.Entries, 2)
+
+	res, err = d.search("cn=bob,ou=people,dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", nil)
+	r.True(ldap3.IsErrorWithCode(err, ldap3.LDAPResultNoSuchObject))
+	r.Nil(res)
+
+	res, err = d.search("", ldap3.ScopeWholeSubtree, "(member=cn=bob,ou=people,dc=acme,dc=tld)", nil)
+	r.Nil(err)
+	r.Len(res.Entries, 1)
+
+	res, err = d.search("", ldap3.ScopeWholeSubtree, "(&(cn=*ea*s)(!(uidNumber<=1000)))", nil)
+	r.Nil(err)
+	r.Len(res.Entries, 1)
+	r.Equal("cn=readers,ou=groups,dc=acme,dc=tld", res.Entries[0].DN)
+
+	_, err = d.search("", ldap3.ScopeWholeSubtree, "(cn:caseExactMatch:=dba)", nil)
+	r.ErrorContains(err, "not supported")
+}
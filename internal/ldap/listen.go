@@ -0,0 +1,176 @@
+// Implements notification of directory changes for watch mode.
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"golang.org/x/exp/slices"
+)
+
+// cf. https://datatracker.ietf.org/doc/html/draft-ietf-ldapext-psearch-03
+const persistentSearchOID = "2.16.840.1.113730.3.4.3"
+
+// ErrNoNotification is returned when directory supports neither Content
+// Synchronization nor Persistent Search.
+var ErrNoNotification = errors.New("directory supports neither syncrepl nor persistent search")
+
+// Listen sends the DN of changed entries matching s to changes until ctx is
+// done.
+//
+// Listen uses RFC 4533 Content Synchronization if supported by directory, or
+// Persistent Search otherwise. Listen reconnects on failure and reports a
+// change since it may have missed one. Sending never blocks: a pending change
+// is enough to trigger a synchronization.
+func Listen(ctx context.Context, s Search, changes chan<- string) error {
+	delay := time.Second
+	for {
+		start := time.Now()
+		err := listen(ctx, s, changes)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrNoNotification) {
+			return fmt.Errorf("%s: %w", s.Base, err)
+		}
+		if time.Since(start) > time.Minute {
+			delay = time.Second
+		}
+		slog.Warn("LDAP listening interrupted. Reconnecting.", "base", s.Base, "err", err, "delay", delay)
+		notify(changes, s.Base)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, 30*time.Second)
+	}
+}
+
+func listen(ctx context.Context, s Search, changes chan<- string) error {
+	c, err := Connect(s.Directory)
+	if err != nil {
+		return err
+	}
+	defer c.Close() //nolint:errcheck
+	if c.Conn == nil {
+		// LDIF or replay.
+		return ErrNoNotification
+	}
+	controls, err := c.supportedControls()
+	if err != nil {
+		return err
+	}
+
+	search := ldap3.SearchRequest{
+		BaseDN: s.Base,
+		Scope:  int(s.Scope),
+		Filter: s.Filter,
+		// Entry DN is enough to trigger synchronization.
+		Attributes: []string{"1.1"},
+	}
+	var res ldap3.Response
+	// Content Synchronization sends all entries before changes.
+	refreshing := false
+	switch {
+	case slices.Contains(controls, ldap3.ControlTypeSyncRequest):
+		slog.Info("Listening LDAP changes with syncrepl.", "base", s.Base, "directory", s.Directory)
+		refreshing = true
+		res = c.Conn.Syncrepl(ctx, &search, 64, ldap3.SyncRequestModeRefreshAndPersist, nil, false)
+	case slices.Contains(controls, persistentSearchOID):
+		slog.Info("Listening LDAP changes with persistent search.", "base", s.Base, "directory", s.Directory)
+		search.Controls = append(search.Controls, controlPersistentSearch{})
+		res = c.Conn.SearchAsync(ctx, &search, 64)
+	default:
+		return ErrNoNotification
+	}
+
+	for res.Next() {
+		if refreshing {
+			refreshing = !refreshDone(res.Controls())
+			if !refreshing {
+				slog.Debug("LDAP syncrepl refresh done.", "base", s.Base)
+			}
+			continue
+		}
+		dn := s.Base
+		if res.Entry() != nil {
+			dn = res.Entry().DN
+		}
+		slog.Debug("LDAP change.", "dn", dn)
+		notify(changes, dn)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if res.Err() != nil {
+		return res.Err()
+	}
+	return errors.New("server ended search")
+}
+
+// refreshDone returns true if controls ends refresh phase of syncrepl.
+func refreshDone(controls []ldap3.Control) bool {
+	for _, control := range controls {
+		info, ok := control.(*ldap3.ControlSyncInfo)
+		if !ok {
+			continue
+		}
+		switch info.Value {
+		case ldap3.SyncInfoRefreshDelete:
+			return info.RefreshDelete.RefreshDone
+		case ldap3.SyncInfoRefreshPresent:
+			return info.RefreshPresent.RefreshDone
+		}
+	}
+	return false
+}
+
+func notify(changes chan<- string, dn string) {
+	select {
+	case changes <- dn:
+	default:
+	}
+}
+
+func (c *Client) supportedControls() ([]string, error) {
+	res, err := c.Search("", ldap3.ScopeBaseObject, "(objectClass=*)", []string{"supportedControl"}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("root DSE: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, fmt.Errorf("root DSE: not found")
+	}
+	return res.Entries[0].GetAttributeValues("supportedControl"), nil
+}
+
+// controlPersistentSearch requests all changes, without initial entries.
+type controlPersistentSearch struct{}
+
+func (controlPersistentSearch) GetControlType() string {
+	return persistentSearchOID
+}
+
+func (controlPersistentSearch) Encode() *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, persistentSearchOID, "Control Type (Persistent Search)"))
+	packet.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "Criticality"))
+	value := ber.Encode(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, nil, "Control Value (Persistent Search)")
+	seq := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "PersistentSearch")
+	// add, delete, modify and modDN.
+	seq.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 15, "changeTypes"))
+	seq.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, true, "changesOnly"))
+	seq.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, false, "returnECs"))
+	value.AppendChild(seq)
+	packet.AppendChild(value)
+	return packet
+}
+
+func (controlPersistentSearch) String() string {
+	return fmt.Sprintf("Control Type: Persistent Search (%q)  Criticality: true", persistentSearchOID)
+}
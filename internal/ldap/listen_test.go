@@ -0,0 +1,54 @@
+package ldap
+
+import (
+	"testing"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshDone(t *testing.T) {
+	r := require.New(t)
+
+	r.False(refreshDone(nil))
+	r.False(refreshDone([]ldap3.Control{&ldap3.ControlSyncInfo{
+		Value:     ldap3.SyncInfoNewcookie,
+		NewCookie: &ldap3.ControlSyncInfoNewCookie{},
+	}}))
+	r.False(refreshDone([]ldap3.Control{&ldap3.ControlSyncInfo{
+		Value:          ldap3.SyncInfoRefreshPresent,
+		RefreshPresent: &ldap3.ControlSyncInfoRefreshPresent{RefreshDone: false},
+	}}))
+	r.True(refreshDone([]ldap3.Control{&ldap3.ControlSyncInfo{
+		Value:          ldap3.SyncInfoRefreshPresent,
+		RefreshPresent: &ldap3.ControlSyncInfoRefreshPresent{RefreshDone: true},
+	}}))
+	r.True(refreshDone([]ldap3.Control{&ldap3.ControlSyncInfo{
+		Value:         ldap3.SyncInfoRefreshDelete,
+		RefreshDelete: &ldap3.ControlSyncInfoRefreshDelete{RefreshDone: true},
+	}}))
+}
+
+func TestNotifyNeverBlocks(t *testing.T) {
+	r := require.New(t)
+
+	changes := make(chan string, 1)
+	notify(changes, "cn=alice,dc=acme,dc=tld")
+	notify(changes, "cn=bob,dc=acme,dc=tld")
+	r.Equal("cn=alice,dc=acme,dc=tld", <-changes)
+	r.Empty(changes)
+}
+
+func TestControlPersistentSearch(t *testing.T) {
+	r := require.New(t)
+
+	packet := controlPersistentSearch{}.Encode()
+	r.Len(packet.Children, 3)
+	r.Equal(persistentSearchOID, packet.Children[0].Value)
+	r.Equal(true, packet.Children[1].Value)
+	seq := packet.Children[2].Children[0]
+	r.Len(seq.Children, 3)
+	r.EqualValues(15, seq.Children[0].Value)
+	r.Equal(true, seq.Children[1].Value)
+	r.Equal(false, seq.Children[2].Value)
+}
@@ -0,0 +1,147 @@
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// matchFilter evaluates a filter compiled by ldap3.CompileFilter against an
+// entry.
+//
+// Values are compared case-insensitively, like caseIgnoreMatch. DN values are
+// compared in their canonical form. Ordering compares integers numerically.
+// Extensible match is not supported.
+func matchFilter(packet *ber.Packet, entry *ldap3.Entry) (bool, error) {
+	switch packet.Tag {
+	case ldap3.FilterAnd:
+		for _, child := range packet.Children {
+			match, err := matchFilter(child, entry)
+			if err != nil || !match {
+				return false, err
+			}
+		}
+		return true, nil
+	case ldap3.FilterOr:
+		for _, child := range packet.Children {
+			match, err := matchFilter(child, entry)
+			if err != nil || match {
+				return match, err
+			}
+		}
+		return false, nil
+	case ldap3.FilterNot:
+		match, err := matchFilter(packet.Children[0], entry)
+		return !match, err
+	case ldap3.FilterPresent:
+		attr := ber.DecodeString(packet.Data.Bytes())
+		// Every entry has an object class, even if LDIF omits it.
+		if strings.EqualFold(attr, "objectClass") {
+			return true, nil
+		}
+		return len(entry.GetEqualFoldAttributeValues(attr)) > 0, nil
+	case ldap3.FilterEqualityMatch, ldap3.FilterApproxMatch:
+		attr, assertion := decodeAssertion(packet)
+		return anyValue(entry, attr, func(value string) bool {
+			return equalValues(value, assertion)
+		}), nil
+	case ldap3.FilterGreaterOrEqual:
+		attr, assertion := decodeAssertion(packet)
+		return anyValue(entry, attr, func(value string) bool {
+			return compareValues(value, assertion) >= 0
+		}), nil
+	case ldap3.FilterLessOrEqual:
+		attr, assertion := decodeAssertion(packet)
+		return anyValue(entry, attr, func(value string) bool {
+			return compareValues(value, assertion) <= 0
+		}), nil
+	case ldap3.FilterSubstrings:
+		attr := ber.DecodeString(packet.Children[0].Data.Bytes())
+		return anyValue(entry, attr, func(value string) bool {
+			return matchSubstrings(strings.ToLower(value), packet.Children[1].Children)
+		}), nil
+	default:
+		filter, _ := ldap3.DecompileFilter(packet)
+		return false, fmt.Errorf("%s: %s not supported", filter, ldap3.FilterMap[uint64(packet.Tag)])
+	}
+}
+
+func decodeAssertion(packet *ber.Packet) (attr, value string) {
+	attr = ber.DecodeString(packet.Children[0].Data.Bytes())
+	value = ber.DecodeString(packet.Children[1].Data.Bytes())
+	return
+}
+
+func anyValue(entry *ldap3.Entry, attr string, f func(string) bool) bool {
+	if strings.EqualFold(attr, "dn") || strings.EqualFold(attr, "distinguishedName") {
+		if f(entry.DN) {
+			return true
+		}
+	}
+	for _, value := range entry.GetEqualFoldAttributeValues(attr) {
+		if f(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(value, assertion string) bool {
+	if strings.EqualFold(value, assertion) {
+		return true
+	}
+	// Compare DN values, ignoring spaces and escaping differences.
+	if !strings.Contains(value, "=") {
+		return false
+	}
+	dn, err := ldap3.ParseDN(value)
+	if err != nil {
+		return false
+	}
+	other, err := ldap3.ParseDN(assertion)
+	if err != nil {
+		return false
+	}
+	return dn.EqualFold(other)
+}
+
+func compareValues(value, assertion string) int {
+	a, errA := strconv.ParseInt(value, 10, 64)
+	b, errB := strconv.ParseInt(assertion, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(value), strings.ToLower(assertion))
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		s := strings.ToLower(ber.DecodeString(part.Data.Bytes()))
+		switch part.Tag {
+		case ldap3.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case ldap3.FilterSubstringsFinal:
+			return strings.HasSuffix(value, s)
+		default:
+			i := strings.Index(value, s)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(s):]
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package ldap
+
+import (
+	"errors"
+	"log/slog"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// Pool shares a bounded set of clients between concurrent searches.
+//
+// Each search borrows a client for its duration. A search never waits for a
+// client while holding another one, thus a pool can't deadlock.
+type Pool struct {
+	clients chan Client
+	cache   *searchCache
+}
+
+// ConnectPool opens size connections to named directory.
+func ConnectPool(directory string, size int) (*Pool, error) {
+	var clients []Client
+	for i := 0; i < size; i++ {
+		c, err := Connect(directory)
+		if err != nil {
+			_ = NewPool(clients...).Close()
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	if size > 1 {
+		slog.Debug("Opened LDAP connection pool.", "directory", directory, "size", size)
+	}
+	return NewPool(clients...), nil
+}
+
+// NewPool shares clients. A client may appear several times if it is safe
+// for concurrent use, like an LDIF client.
+func NewPool(clients ...Client) *Pool {
+	p := &Pool{
+		clients: make(chan Client, len(clients)),
+		cache:   &searchCache{searches: make(map[searchKey]*cachedSearch)},
+	}
+	for _, c := range clients {
+		p.clients <- c
+	}
+	return p
+}
+
+// Size returns the maximum number of concurrent searches.
+func (p *Pool) Size() int {
+	return cap(p.clients)
+}
+
+// Close all connections of pool. Clients may have reconnected, close them
+// once returned to pool.
+func (p *Pool) Close() error {
+	var errs []error
+	for range cap(p.clients) {
+		c := <-p.clients
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Search borrows a client to search directory. The client returned to pool
+// may have reconnected. pageSize overrides PAGE_SIZE
+// from ldaprc if not nil.
+func (p *Pool) Search(base string, scope Scope, filter string, attributes []string, pageSize *uint32) (*ldap3.SearchResult, error) {
+	c := <-p.clients
+	defer func() { p.clients <- c }()
+	return c.Search(base, scope, filter, attributes, c.ResolvePageSize(pageSize))
+}
+
+// rootDSE borrows a client to read root DSE. An LDIF export has no root DSE.
+func (p *Pool) rootDSE() (*RootDSE, error) {
+	c := <-p.clients
+	defer func() { p.clients <- c }()
+	if c.ldif != nil {
+		return &RootDSE{}, nil
+	}
+	return c.ReadRootDSE()
+}
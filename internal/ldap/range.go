@@ -0,0 +1,106 @@
+// Implements Active Directory ranged attribute retrieval.
+//
+// cf. https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-adts/d2435927-0999-4c62-8c6d-13ba31a52e1a
+package ldap
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// parseRange splits a ranged attribute description like member;range=0-1499.
+//
+// last is true if the range is the last one, i.e. member;range=1500-*.
+func parseRange(name string) (attr string, high int, last bool, ok bool) {
+	attr, options, found := strings.Cut(name, ";")
+	if !found {
+		return name, 0, false, false
+	}
+	for _, option := range strings.Split(options, ";") {
+		bounds, found := strings.CutPrefix(strings.ToLower(option), "range=")
+		if !found {
+			continue
+		}
+		_, rawHigh, found := strings.Cut(bounds, "-")
+		if !found {
+			return name, 0, false, false
+		}
+		if rawHigh == "*" {
+			return attr, 0, true, true
+		}
+		high, err := strconv.Atoi(rawHigh)
+		if err != nil {
+			return name, 0, false, false
+		}
+		return attr, high, false, true
+	}
+	return name, 0, false, false
+}
+
+// expandRanges fetches remaining values of ranged attributes of entries.
+//
+// Entries attributes are rewritten without range option, with all values.
+func (c *Client) expandRanges(entries []*ldap3.Entry) error {
+	for _, entry := range entries {
+		for _, attribute := range entry.Attributes {
+			name, high, last, ok := parseRange(attribute.Name)
+			if !ok {
+				continue
+			}
+			attribute.Name = name
+			for !last {
+				values, byteValues, next, err := c.searchRange(entry.DN, name, high+1)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", entry.DN, name, err)
+				}
+				if 0 <= next && next <= high {
+					return fmt.Errorf("%s: %s: range does not progress", entry.DN, name)
+				}
+				attribute.Values = append(attribute.Values, values...)
+				attribute.ByteValues = append(attribute.ByteValues, byteValues...)
+				high, last = next, next < 0
+			}
+			slog.Debug("Retrieved all values of ranged attribute.", "dn", entry.DN, "attribute", name, "values", len(attribute.Values))
+		}
+	}
+	return nil
+}
+
+// searchRange returns values of attr starting at low. next is -1 if there is
+// no more values.
+func (c *Client) searchRange(dn, attr string, low int) (values []string, byteValues [][]byte, next int, err error) {
+	rangedAttr := fmt.Sprintf("%s;range=%d-*", attr, low)
+	search := ldap3.SearchRequest{
+		BaseDN:     dn,
+		Scope:      ldap3.ScopeBaseObject,
+		Filter:     "(objectClass=*)",
+		Attributes: []string{rangedAttr},
+	}
+	slog.Debug("Retrieving range of attribute values.", "cmd", c.Command("ldapsearch", "-b", dn, "-s", "base", search.Filter, rangedAttr))
+	var res *ldap3.SearchResult
+	Watch.TimeIt(func() {
+		res, err = c.conn().Search(&search)
+	})
+	if err != nil {
+		return nil, nil, -1, err
+	}
+	if len(res.Entries) != 1 {
+		return nil, nil, -1, fmt.Errorf("entry not found")
+	}
+	for _, attribute := range res.Entries[0].Attributes {
+		name, high, last, ok := parseRange(attribute.Name)
+		if !ok || !strings.EqualFold(name, attr) {
+			continue
+		}
+		if last {
+			high = -1
+		}
+		return attribute.Values, attribute.ByteValues, high, nil
+	}
+	// Server returns no values after the last range.
+	return nil, nil, -1, nil
+}
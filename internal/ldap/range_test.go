@@ -0,0 +1,65 @@
+package ldap
+
+import (
+	"fmt"
+	"testing"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	r := require.New(t)
+
+	attr, high, last, ok := parseRange("member;range=0-1499")
+	r.True(ok)
+	r.False(last)
+	r.Equal("member", attr)
+	r.Equal(1499, high)
+
+	attr, _, last, ok = parseRange("member;Range=1500-*")
+	r.True(ok)
+	r.True(last)
+	r.Equal("member", attr)
+
+	attr, _, _, ok = parseRange("member")
+	r.False(ok)
+	r.Equal("member", attr)
+
+	attr, _, _, ok = parseRange("cn;lang-fr")
+	r.False(ok)
+	r.Equal("cn;lang-fr", attr)
+}
+
+func TestExpandRanges(t *testing.T) {
+	r := require.New(t)
+
+	dn := "cn=big,ou=groups,dc=acme,dc=tld"
+	fake := &fakeConn{}
+	answerRange(fake, dn, 2, "member;range=2-3", "cn=c", "cn=d")
+	answerRange(fake, dn, 4, "member;range=4-*", "cn=e")
+
+	c := Client{searcher: fake}
+	entry := ldap3.NewEntry(dn, map[string][]string{
+		"cn":               {"big"},
+		"member;range=0-1": {"cn=a", "cn=b"},
+	})
+	r.Nil(c.expandRanges([]*ldap3.Entry{entry}))
+	r.Equal([]string{"cn=a", "cn=b", "cn=c", "cn=d", "cn=e"}, entry.GetAttributeValues("member"))
+	r.Equal("big", entry.GetAttributeValue("cn"))
+
+	// Server returns a range not progressing.
+	fake = &fakeConn{}
+	answerRange(fake, dn, 2, "member;range=0-1", "cn=a", "cn=b")
+	c = Client{searcher: fake}
+	entry = ldap3.NewEntry(dn, map[string][]string{"member;range=0-1": {"cn=a", "cn=b"}})
+	r.ErrorContains(c.expandRanges([]*ldap3.Entry{entry}), "range does not progress")
+}
+
+// answerRange sets the response of the search of values of member from low.
+func answerRange(fake *fakeConn, dn string, low int, name string, values ...string) {
+	attributes := []string{fmt.Sprintf("member;range=%d-*", low)}
+	fake.answer(dn, attributes, &ldap3.SearchResult{Entries: []*ldap3.Entry{
+		ldap3.NewEntry(dn, map[string][]string{name: values}),
+	}})
+}
@@ -0,0 +1,164 @@
+// Implements recording and replay of searches for debugging.
+package ldap
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// RecordDir is the directory where to save every search. Empty disables
+// recording.
+var RecordDir string
+
+// ReplayDir is the directory where to read recorded searches instead of
+// searching the directory. Empty disables replay.
+var ReplayDir string
+
+// recordedSearch is the JSON representation of a search and its result.
+type recordedSearch struct {
+	Directory  string          `json:"directory,omitempty"`
+	Base       string          `json:"base"`
+	Scope      string          `json:"scope"`
+	Filter     string          `json:"filter"`
+	Attributes []string        `json:"attributes"`
+	Entries    []recordedEntry `json:"entries"`
+	Error      *recordedError  `json:"error,omitempty"`
+}
+
+type recordedEntry struct {
+	DN         string              `json:"dn"`
+	Attributes []recordedAttribute `json:"attributes"`
+}
+
+type recordedAttribute struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+	// Values are base64 encoded because at least one is not valid UTF-8.
+	Base64 bool `json:"base64,omitempty"`
+}
+
+type recordedError struct {
+	Code    uint16 `json:"code"`
+	Message string `json:"message"`
+}
+
+// recordPath returns the file recording a search. Path does not depends on
+// page size since paging does not change result.
+func recordPath(dir, directory, base string, scope Scope, filter string, attributes []string) string {
+	k := []any{base, scope.String(), filter, attributes}
+	if directory != "" {
+		k = append([]any{directory}, k...)
+	}
+	key, _ := json.Marshal(k)
+	sum := sha256.Sum256(key)
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+func recordSearch(dir, directory, base string, scope Scope, filter string, attributes []string, res *ldap3.SearchResult, searchErr error) error {
+	r := recordedSearch{
+		Directory:  directory,
+		Base:       base,
+		Scope:      scope.String(),
+		Filter:     filter,
+		Attributes: attributes,
+		Entries:    []recordedEntry{},
+	}
+	if searchErr != nil {
+		r.Error = &recordedError{Message: searchErr.Error()}
+		var ldapErr *ldap3.Error
+		if errors.As(searchErr, &ldapErr) && ldapErr.Err != nil {
+			r.Error.Code = ldapErr.ResultCode
+			r.Error.Message = ldapErr.Err.Error()
+		}
+	} else {
+		for _, entry := range res.Entries {
+			r.Entries = append(r.Entries, recordEntry(entry))
+		}
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := recordPath(dir, directory, base, scope, filter, attributes)
+	slog.Debug("Recording LDAP search.", "path", path)
+	return os.WriteFile(path, data, 0o600)
+}
+
+func recordEntry(entry *ldap3.Entry) recordedEntry {
+	out := recordedEntry{DN: entry.DN, Attributes: []recordedAttribute{}}
+	for _, attribute := range entry.Attributes {
+		a := recordedAttribute{Name: attribute.Name, Values: attribute.Values}
+		for _, value := range attribute.ByteValues {
+			if !utf8.Valid(value) {
+				a.Base64 = true
+				break
+			}
+		}
+		if a.Base64 {
+			a.Values = nil
+			for _, value := range attribute.ByteValues {
+				a.Values = append(a.Values, base64.StdEncoding.EncodeToString(value))
+			}
+		}
+		out.Attributes = append(out.Attributes, a)
+	}
+	return out
+}
+
+func replaySearch(dir, directory, base string, scope Scope, filter string, attributes []string) (*ldap3.SearchResult, error) {
+	path := recordPath(dir, directory, base, scope, filter, attributes)
+	slog.Debug("Replaying LDAP search.", "path", path, "base", base, "scope", scope, "filter", filter, "attributes", attributes)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("search not recorded: base=%s scope=%s filter=%s", base, scope, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r recordedSearch
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Error != nil {
+		err = errors.New(r.Error.Message)
+		if r.Error.Code != 0 {
+			err = ldap3.NewError(r.Error.Code, err)
+		}
+		return nil, err
+	}
+
+	res := &ldap3.SearchResult{}
+	for _, e := range r.Entries {
+		entry := &ldap3.Entry{DN: e.DN}
+		for _, a := range e.Attributes {
+			attribute := &ldap3.EntryAttribute{Name: a.Name}
+			for _, value := range a.Values {
+				raw := []byte(value)
+				if a.Base64 {
+					raw, err = base64.StdEncoding.DecodeString(value)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %s: %w", path, a.Name, err)
+					}
+				}
+				attribute.Values = append(attribute.Values, string(raw))
+				attribute.ByteValues = append(attribute.ByteValues, raw)
+			}
+			entry.Attributes = append(entry.Attributes, attribute)
+		}
+		res.Entries = append(res.Entries, entry)
+	}
+	slog.Debug("LDAP search replayed.", "entries", len(res.Entries))
+	return res, nil
+}
@@ -0,0 +1,55 @@
+package ldap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReplay(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.ldif")
+	r.Nil(os.WriteFile(path, []byte(testLDIF), 0o600))
+	c, err := OpenLDIF(path)
+	r.Nil(err)
+
+	RecordDir = filepath.Join(dir, "recording")
+	defer func() { RecordDir = "" }()
+	r.Nil(os.Mkdir(RecordDir, 0o700))
+
+	recorded, err := c.Search("ou=groups,dc=acme,dc=tld", ldap3.ScopeWholeSubtree, "(objectClass=groupOfNames)", []string{"cn", "member"}, 0)
+	r.Nil(err)
+	r.Len(recorded.Entries, 2)
+	_, err = c.Search("cn=bob,ou=people,dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", nil, 0)
+	r.NotNil(err)
+
+	replay := Client{replay: RecordDir}
+	RecordDir = ""
+	replayed, err := replay.Search("ou=groups,dc=acme,dc=tld", ldap3.ScopeWholeSubtree, "(objectClass=groupOfNames)", []string{"cn", "member"}, 1000)
+	r.Nil(err)
+	r.Equal(recorded.Entries, replayed.Entries)
+
+	_, err = replay.Search("cn=bob,ou=people,dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", nil, 0)
+	r.True(ldap3.IsErrorWithCode(err, ldap3.LDAPResultNoSuchObject))
+
+	_, err = replay.Search("dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", nil, 0)
+	r.ErrorContains(err, "not recorded")
+}
+
+func TestRecordBinaryValues(t *testing.T) {
+	r := require.New(t)
+
+	entry := &ldap3.Entry{DN: "cn=toto", Attributes: []*ldap3.EntryAttribute{{
+		Name:       "objectGUID",
+		Values:     []string{"\xff\x00"},
+		ByteValues: [][]byte{{0xff, 0x00}},
+	}}}
+	recorded := recordEntry(entry)
+	r.True(recorded.Attributes[0].Base64)
+	r.Equal([]string{"/wA="}, recorded.Attributes[0].Values)
+}
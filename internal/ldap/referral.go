@@ -0,0 +1,190 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldap3 "github.com/go-ldap/ldap/v3"
+)
+
+// Maximum number of referrals followed from the first search.
+const maxReferralHops = 5
+
+type referral struct {
+	url  string
+	hops int
+}
+
+// followReferrals chases continuation references and referral result of a
+// search. Referred servers are searched with the same credentials. Entries are
+// deduplicated by DN.
+func (c *Client) followReferrals(scope Scope, filter string, attributes []string, pageSize uint32, res *ldap3.SearchResult, err error) (*ldap3.SearchResult, error) {
+	var queue []referral
+	if err != nil {
+		urls := referralsOfError(err)
+		if len(urls) == 0 {
+			return nil, err
+		}
+		res = &ldap3.SearchResult{}
+		queue = appendReferrals(queue, urls, 1)
+	} else {
+		queue = appendReferrals(queue, res.Referrals, 1)
+	}
+	if len(queue) == 0 {
+		return res, nil
+	}
+
+	seen := mapset.NewThreadUnsafeSet[string]()
+	for _, entry := range res.Entries {
+		seen.Add(NormalizeDN(entry.DN))
+	}
+	visited := mapset.NewThreadUnsafeSet[string]()
+	dial := c.connectReferral
+	if c.dialReferral != nil {
+		dial = c.dialReferral
+	}
+	clients := make(map[string]*Client)
+	defer func() {
+		for _, client := range clients {
+			_ = client.Close()
+		}
+	}()
+
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		if !visited.Add(ref.url) {
+			continue
+		}
+		if ref.hops > maxReferralHops {
+			return nil, ldap3.NewError(ldap3.LDAPResultReferralLimitExceeded, fmt.Errorf("%s: more than %d hops", ref.url, maxReferralHops))
+		}
+		uri, base, refScope, refFilter, err := parseReferral(ref.url)
+		if err != nil {
+			return nil, fmt.Errorf("referral: %s: %w", ref.url, err)
+		}
+		if refScope < 0 {
+			refScope = scope
+		}
+		if refFilter == "" {
+			refFilter = filter
+		}
+
+		client, ok := clients[uri]
+		if !ok {
+			client, err = dial(uri)
+			if err != nil {
+				return nil, fmt.Errorf("referral: %s: %w", uri, err)
+			}
+			clients[uri] = client
+		}
+		slog.Debug("Following LDAP referral.", "url", ref.url, "hops", ref.hops)
+		refRes, err := client.searchDirectory(base, refScope, refFilter, attributes, pageSize)
+		if err != nil {
+			urls := referralsOfError(err)
+			if len(urls) == 0 {
+				return nil, fmt.Errorf("referral: %s: %w", ref.url, err)
+			}
+			queue = appendReferrals(queue, urls, ref.hops+1)
+			continue
+		}
+		for _, entry := range refRes.Entries {
+			if seen.Add(NormalizeDN(entry.DN)) {
+				res.Entries = append(res.Entries, entry)
+			}
+		}
+		queue = appendReferrals(queue, refRes.Referrals, ref.hops+1)
+	}
+	res.Referrals = nil
+	return res, nil
+}
+
+func appendReferrals(queue []referral, urls []string, hops int) []referral {
+	for _, u := range urls {
+		queue = append(queue, referral{url: u, hops: hops})
+	}
+	return queue
+}
+
+// connectReferral connects and binds to referred server with credentials of
+// client.
+func (c *Client) connectReferral(uri string) (*Client, error) {
+	referred := &Client{
+		Directory: c.Directory,
+		StartTLS:  c.StartTLS,
+		Timeout:   c.Timeout,
+		PageSize:  c.PageSize,
+		rootDSE:   &RootDSE{},
+		uris:      []string{uri},
+		tls:       c.tls,
+		options:   c.options,
+	}
+	err := referred.dial()
+	if err != nil {
+		return nil, err
+	}
+	err = referred.bind()
+	if err != nil {
+		_ = referred.Close()
+		return nil, err
+	}
+	return referred, nil
+}
+
+// parseReferral splits an LDAP URL like ldap://host/dn??scope?filter. scope is
+// -1 if URL does not define scope.
+//
+// cf. https://www.rfc-editor.org/rfc/rfc4516
+func parseReferral(rawURL string) (uri, base string, scope Scope, filter string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		err = fmt.Errorf("unsupported scheme %s", u.Scheme)
+		return
+	}
+	uri = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	base = strings.TrimPrefix(u.Path, "/")
+	scope = -1
+	parts := strings.Split(u.RawQuery, "?")
+	if len(parts) > 1 && parts[1] != "" {
+		scope, err = ParseScope(parts[1])
+		if err != nil {
+			return
+		}
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		// + is not a space in a filter.
+		filter, err = url.PathUnescape(parts[2])
+	}
+	return
+}
+
+// referralsOfError returns referral URLs of a referral result.
+func referralsOfError(err error) (urls []string) {
+	var ldapErr *ldap3.Error
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap3.LDAPResultReferral || ldapErr.Packet == nil {
+		return nil
+	}
+	if len(ldapErr.Packet.Children) < 2 {
+		return nil
+	}
+	// LDAPResult ::= SEQUENCE { resultCode, matchedDN, diagnosticMessage, referral [3] Referral OPTIONAL }
+	for _, child := range ldapErr.Packet.Children[1].Children {
+		if child.ClassType != ber.ClassContext || child.Tag != 3 {
+			continue
+		}
+		for _, u := range child.Children {
+			if s, ok := u.Value.(string); ok {
+				urls = append(urls, s)
+			}
+		}
+	}
+	return urls
+}
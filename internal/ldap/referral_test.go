@@ -0,0 +1,135 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReferral(t *testing.T) {
+	r := require.New(t)
+
+	uri, base, scope, filter, err := parseReferral("ldap://child.acme.tld/dc=child,dc=acme,dc=tld")
+	r.NoError(err)
+	r.Equal("ldap://child.acme.tld", uri)
+	r.Equal("dc=child,dc=acme,dc=tld", base)
+	r.Equal(Scope(-1), scope)
+	r.Equal("", filter)
+
+	uri, base, scope, filter, err = parseReferral("ldaps://dc1:636/ou=IT%20Staff,dc=acme,dc=tld??sub?(objectClass=group)")
+	r.NoError(err)
+	r.Equal("ldaps://dc1:636", uri)
+	r.Equal("ou=IT Staff,dc=acme,dc=tld", base)
+	r.Equal(ldap3.ScopeWholeSubtree, int(scope))
+	r.Equal("(objectClass=group)", filter)
+
+	_, _, _, filter, err = parseReferral("ldap://dc1/dc=acme,dc=tld??sub?(cn=a+b%29)")
+	r.NoError(err)
+	r.Equal("(cn=a+b))", filter)
+
+	_, _, _, _, err = parseReferral("http://acme.tld/")
+	r.ErrorContains(err, "unsupported scheme")
+}
+
+func TestReferralsOfError(t *testing.T) {
+	r := require.New(t)
+
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 1, "MessageID"))
+	done := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap3.ApplicationSearchResultDone, nil, "Search Result Done")
+	done.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, ldap3.LDAPResultReferral, "resultCode"))
+	done.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	done.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	referral := ber.Encode(ber.ClassContext, ber.TypeConstructed, 3, nil, "Referral")
+	referral.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "ldap://child.acme.tld/dc=child,dc=acme,dc=tld", "URI"))
+	done.AppendChild(referral)
+	packet.AppendChild(done)
+
+	err := &ldap3.Error{ResultCode: ldap3.LDAPResultReferral, Err: errors.New("referral"), Packet: packet}
+	r.Equal([]string{"ldap://child.acme.tld/dc=child,dc=acme,dc=tld"}, referralsOfError(err))
+
+	r.Nil(referralsOfError(ldap3.NewError(ldap3.LDAPResultNoSuchObject, errors.New("missing"))))
+	r.Nil(referralsOfError(errors.New("bad filter")))
+}
+
+func TestFollowReferralsNone(t *testing.T) {
+	r := require.New(t)
+
+	c := Client{Referrals: true}
+	res := &ldap3.SearchResult{Entries: []*ldap3.Entry{{DN: "cn=alice,dc=acme,dc=tld"}}}
+	out, err := c.followReferrals(ldap3.ScopeWholeSubtree, "(cn=*)", nil, 0, res, nil)
+	r.NoError(err)
+	r.Same(res, out)
+
+	searchErr := ldap3.NewError(ldap3.LDAPResultNoSuchObject, errors.New("missing"))
+	_, err = c.followReferrals(ldap3.ScopeWholeSubtree, "(cn=*)", nil, 0, nil, searchErr)
+	r.ErrorIs(err, searchErr)
+}
+
+func TestFollowReferrals(t *testing.T) {
+	r := require.New(t)
+
+	servers := map[string]*fakeConn{}
+	c := Client{Referrals: true, dialReferral: fakeReferrals(servers)}
+	// child refers to grandchild, which refers back to child and returns
+	// alice again.
+	servers["ldap://child.acme.tld"] = &fakeConn{}
+	servers["ldap://child.acme.tld"].answer("dc=child,dc=acme,dc=tld", []string{"cn"}, &ldap3.SearchResult{
+		Entries:   []*ldap3.Entry{ldap3.NewEntry("cn=bob,dc=child,dc=acme,dc=tld", map[string][]string{"cn": {"bob"}})},
+		Referrals: []string{"ldap://grandchild.acme.tld/dc=grandchild,dc=acme,dc=tld"},
+	})
+	servers["ldap://grandchild.acme.tld"] = &fakeConn{}
+	servers["ldap://grandchild.acme.tld"].answer("dc=grandchild,dc=acme,dc=tld", []string{"cn"}, &ldap3.SearchResult{
+		Entries: []*ldap3.Entry{
+			ldap3.NewEntry("CN=Alice,dc=acme,dc=tld", map[string][]string{"cn": {"alice"}}),
+			ldap3.NewEntry("cn=carol,dc=grandchild,dc=acme,dc=tld", map[string][]string{"cn": {"carol"}}),
+		},
+		Referrals: []string{"ldap://child.acme.tld/dc=child,dc=acme,dc=tld??sub?(cn=a+b)"},
+	})
+
+	res := &ldap3.SearchResult{
+		Entries:   []*ldap3.Entry{ldap3.NewEntry("cn=alice,dc=acme,dc=tld", map[string][]string{"cn": {"alice"}})},
+		Referrals: []string{"ldap://child.acme.tld/dc=child,dc=acme,dc=tld??sub?(cn=a+b)"},
+	}
+	out, err := c.followReferrals(ldap3.ScopeWholeSubtree, "(cn=*)", []string{"cn"}, 0, res, nil)
+	r.NoError(err)
+	var names []string
+	for _, entry := range out.Entries {
+		names = append(names, entry.GetAttributeValue("cn"))
+	}
+	r.Equal([]string{"alice", "bob", "carol"}, names)
+	r.Empty(out.Referrals)
+}
+
+func TestFollowReferralsHops(t *testing.T) {
+	r := require.New(t)
+
+	servers := map[string]*fakeConn{}
+	c := Client{Referrals: true, dialReferral: fakeReferrals(servers)}
+	// Each server refers to the next one, beyond hop limit.
+	for i := 1; i <= maxReferralHops+1; i++ {
+		server := &fakeConn{}
+		server.answer("dc=acme,dc=tld", nil, &ldap3.SearchResult{Referrals: []string{fmt.Sprintf("ldap://dc%d.acme.tld/dc=acme,dc=tld", i+1)}})
+		servers[fmt.Sprintf("ldap://dc%d.acme.tld", i)] = server
+	}
+
+	res := &ldap3.SearchResult{Referrals: []string{"ldap://dc1.acme.tld/dc=acme,dc=tld"}}
+	_, err := c.followReferrals(ldap3.ScopeWholeSubtree, "(cn=*)", nil, 0, res, nil)
+	r.True(ldap3.IsErrorWithCode(err, ldap3.LDAPResultReferralLimitExceeded), "%v", err)
+	r.ErrorContains(err, fmt.Sprintf("ldap://dc%d.acme.tld/dc=acme,dc=tld: more than %d hops", maxReferralHops+1, maxReferralHops))
+}
+
+// fakeReferrals connects referrals to fake servers by URI.
+func fakeReferrals(servers map[string]*fakeConn) func(string) (*Client, error) {
+	return func(uri string) (*Client, error) {
+		server, ok := servers[uri]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown server", uri)
+		}
+		return &Client{Directory: uri, searcher: server}, nil
+	}
+}
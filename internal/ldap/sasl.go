@@ -0,0 +1,72 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3/gssapi"
+)
+
+// externalBind authenticates with TLS client certificate or Unix socket
+// credentials.
+func (c *Client) externalBind() error {
+	if !strings.HasPrefix(c.URI, "ldapi://") && c.options.String("TLS_CERT") == "" {
+		return errors.New("SASL/EXTERNAL requires TLS_CERT or ldapi:// URI")
+	}
+	if !strings.HasPrefix(c.URI, "ldaps://") && !strings.HasPrefix(c.URI, "ldapi://") && !c.StartTLS {
+		return errors.New("SASL/EXTERNAL requires TLS")
+	}
+	slog.Debug("LDAP SASL/EXTERNAL bind.", "uri", c.URI)
+	return c.Conn.ExternalBind()
+}
+
+// gssapiBind authenticates with Kerberos.
+//
+// If KRB5_CLIENT_KTNAME is defined, gets credentials from keytab for principal
+// SASL_AUTHCID. Otherwise, reuses credentials cache from KRB5CCNAME, like kinit.
+func (c *Client) gssapiBind() error {
+	u, err := url.Parse(c.URI)
+	if err != nil {
+		return err
+	}
+	krb5conf := os.Getenv("KRB5_CONFIG")
+	if krb5conf == "" {
+		krb5conf = "/etc/krb5.conf"
+	}
+
+	var krbc *gssapi.Client
+	keytab := strings.TrimPrefix(os.Getenv("KRB5_CLIENT_KTNAME"), "FILE:")
+	if keytab != "" {
+		username, realm, found := strings.Cut(c.SaslAuthCID, "@")
+		if !found {
+			return errors.New("SASL_AUTHCID must be a Kerberos principal like user@REALM")
+		}
+		slog.Debug("Loading Kerberos credentials from keytab.", "keytab", keytab, "principal", c.SaslAuthCID)
+		krbc, err = gssapi.NewClientWithKeytab(username, realm, keytab, krb5conf)
+	} else {
+		ccache := defaultCCache()
+		slog.Debug("Loading Kerberos credentials cache.", "ccache", ccache)
+		krbc, err = gssapi.NewClientFromCCache(ccache, krb5conf)
+	}
+	if err != nil {
+		return fmt.Errorf("kerberos: %w", err)
+	}
+	defer krbc.Close() //nolint:errcheck
+
+	spn := "ldap/" + u.Hostname()
+	slog.Debug("LDAP SASL/GSSAPI bind.", "spn", spn, "authzid", c.SaslAuthzID)
+	return c.Conn.GSSAPIBind(krbc, spn, c.SaslAuthzID)
+}
+
+// defaultCCache returns credentials cache path like MIT Kerberos.
+func defaultCCache() string {
+	ccache := os.Getenv("KRB5CCNAME")
+	if ccache != "" {
+		return strings.TrimPrefix(ccache, "FILE:")
+	}
+	return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+}
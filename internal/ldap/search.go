@@ -1,25 +1,38 @@
 package ldap
 
-import "golang.org/x/exp/maps"
+import (
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
 
 type Search struct {
-	Base        string
-	Scope       Scope
-	Filter      string
-	Attributes  []string
-	Subsearches map[string]Subsearch `mapstructure:"joins"`
+	Base           string
+	Scope          Scope
+	Filter         string
+	Attributes     []string
+	PageSize       *uint32              `mapstructure:"page_size"`
+	Subsearches    map[string]Subsearch `mapstructure:"joins"`
+	OnUnexpectedDN string               `mapstructure:"on_unexpected_dn"`
+	// Path to an LDIF file to search instead of directory.
+	LDIF string `mapstructure:"ldif"`
+	// Name of directory to search. Empty for default directory.
+	Directory string
 }
 
-func (s Search) SubsearchAttribute() string {
+// SubsearchAttributes returns sorted attributes triggering a sub-search.
+func (s Search) SubsearchAttributes() []string {
 	keys := maps.Keys(s.Subsearches)
-	if len(keys) == 0 {
-		return ""
-	}
-	return keys[0]
+	slices.Sort(keys)
+	return keys
 }
 
 type Subsearch struct {
 	Filter     string
 	Scope      Scope
 	Attributes []string
+	PageSize   *uint32 `mapstructure:"page_size"`
+	// Expand nested groups.
+	Transitive bool
+	// Number of DN per batched search. 0 disables batching.
+	BatchSize int `mapstructure:"batch_size"`
 }
@@ -0,0 +1,188 @@
+package ldap
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn is a directory server searching an LDIF export. Canned results
+// override searches by base and attributes. Like Active Directory, fakeConn
+// matches memberOf with LDAP_MATCHING_RULE_IN_CHAIN.
+type fakeConn struct {
+	ldif    *ldifDirectory
+	results map[string]*ldap3.SearchResult
+	// Maximum number of entries returned by a search or a page. 0 means
+	// unlimited.
+	sizeLimit int
+	// Number of pages returned by paged searches.
+	pages int
+}
+
+func newFakeConn(t *testing.T, ldif string) *fakeConn {
+	d, err := readLDIF(strings.NewReader(ldif))
+	require.NoError(t, err)
+	return &fakeConn{ldif: d}
+}
+
+// answer sets canned result of search of base and attributes.
+func (f *fakeConn) answer(base string, attributes []string, res *ldap3.SearchResult) {
+	if f.results == nil {
+		f.results = make(map[string]*ldap3.SearchResult)
+	}
+	f.results[fakeKey(base, attributes)] = res
+}
+
+func fakeKey(base string, attributes []string) string {
+	return base + " " + strings.Join(attributes, ",")
+}
+
+func (f *fakeConn) Search(search *ldap3.SearchRequest) (*ldap3.SearchResult, error) {
+	if res, ok := f.results[fakeKey(search.BaseDN, search.Attributes)]; ok {
+		return res, nil
+	}
+	res, err := f.search(search)
+	if err != nil {
+		return nil, err
+	}
+	if 0 < f.sizeLimit && f.sizeLimit < len(res.Entries) {
+		res.Entries = res.Entries[:f.sizeLimit]
+		return res, ldap3.NewError(ldap3.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))
+	}
+	return res, nil
+}
+
+func (f *fakeConn) SearchWithPaging(search *ldap3.SearchRequest, pagingSize uint32) (*ldap3.SearchResult, error) {
+	if res, ok := f.results[fakeKey(search.BaseDN, search.Attributes)]; ok {
+		f.pages++
+		return res, nil
+	}
+	if 0 < f.sizeLimit && f.sizeLimit < int(pagingSize) {
+		// Page exceeds size limit.
+		return f.Search(search)
+	}
+	res, err := f.search(search)
+	if err != nil {
+		return nil, err
+	}
+	f.pages += max(1, (len(res.Entries)+int(pagingSize)-1)/int(pagingSize))
+	return res, nil
+}
+
+// inChainFilter matches memberOf assertion with LDAP_MATCHING_RULE_IN_CHAIN.
+var inChainFilter = regexp.MustCompile(`\(memberOf:` + regexp.QuoteMeta(inChainOID) + `:=([^)]*)\)`)
+
+func (f *fakeConn) search(search *ldap3.SearchRequest) (*ldap3.SearchResult, error) {
+	m := inChainFilter.FindStringSubmatch(search.Filter)
+	if m == nil {
+		return f.ldif.search(search.BaseDN, Scope(search.Scope), search.Filter, search.Attributes)
+	}
+	filter := strings.Replace(search.Filter, m[0], "(objectClass=*)", 1)
+	res, err := f.ldif.search(search.BaseDN, Scope(search.Scope), filter, search.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	members := f.transitiveMembers(m[1])
+	var entries []*ldap3.Entry
+	for _, entry := range res.Entries {
+		if members.Contains(NormalizeDN(entry.DN)) {
+			entries = append(entries, entry)
+		}
+	}
+	res.Entries = entries
+	return res, nil
+}
+
+// transitiveMembers returns normalized DN of members of group, including
+// nested groups.
+func (f *fakeConn) transitiveMembers(group string) mapset.Set[string] {
+	members := mapset.NewThreadUnsafeSet[string]()
+	queue := []string{NormalizeDN(group)}
+	for len(queue) > 0 {
+		dn := queue[0]
+		queue = queue[1:]
+		for _, entry := range f.ldif.entries {
+			if NormalizeDN(entry.DN) != dn {
+				continue
+			}
+			for _, member := range entry.GetEqualFoldAttributeValues("member") {
+				if members.Add(NormalizeDN(member)) {
+					queue = append(queue, NormalizeDN(member))
+				}
+			}
+		}
+	}
+	return members
+}
+
+var pagingLDIF = `
+dn: ou=people,dc=acme,dc=tld
+ou: people
+
+dn: cn=alice,ou=people,dc=acme,dc=tld
+cn: alice
+
+dn: cn=bob,ou=people,dc=acme,dc=tld
+cn: bob
+
+dn: cn=carol,ou=people,dc=acme,dc=tld
+cn: carol
+
+dn: cn=dave,ou=people,dc=acme,dc=tld
+cn: dave
+
+dn: cn=eve,ou=people,dc=acme,dc=tld
+cn: eve
+`
+
+func TestSearchPaging(t *testing.T) {
+	r := require.New(t)
+
+	fake := newFakeConn(t, pagingLDIF)
+	fake.sizeLimit = 2
+	// PAGE_SIZE from ldaprc.
+	p := NewPool(Client{PageSize: 2, searcher: fake})
+	res, err := p.Search("ou=people,dc=acme,dc=tld", ldap3.ScopeSingleLevel, "(cn=*)", []string{"cn"}, nil)
+	r.NoError(err)
+	r.Len(res.Entries, 5)
+	r.Equal(3, fake.pages)
+
+	// page_size from search overrides PAGE_SIZE.
+	fake.pages = 0
+	pageSize := uint32(1)
+	res, err = p.Search("ou=people,dc=acme,dc=tld", ldap3.ScopeSingleLevel, "(cn=*)", []string{"cn"}, &pageSize)
+	r.NoError(err)
+	r.Len(res.Entries, 5)
+	r.Equal(5, fake.pages)
+}
+
+func TestSearchSizeLimitExceeded(t *testing.T) {
+	r := require.New(t)
+
+	fake := newFakeConn(t, pagingLDIF)
+	fake.sizeLimit = 2
+	c := Client{searcher: fake}
+	_, err := c.Search("ou=people,dc=acme,dc=tld", ldap3.ScopeSingleLevel, "(cn=*)", []string{"cn"}, 0)
+	r.ErrorContains(err, "configure paging")
+	r.True(ldap3.IsErrorWithCode(err, ldap3.LDAPResultSizeLimitExceeded), "%v", err)
+	r.Zero(fake.pages)
+
+	// Page larger than server size limit.
+	_, err = c.Search("ou=people,dc=acme,dc=tld", ldap3.ScopeSingleLevel, "(cn=*)", []string{"cn"}, 3)
+	r.ErrorContains(err, "configure paging")
+}
+
+func TestSearchNotConnected(t *testing.T) {
+	r := require.New(t)
+
+	// A failed reconnection leaves client without connection.
+	c := Client{}
+	_, err := c.searchDirectory("dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", nil, 0)
+	r.ErrorContains(err, "not connected")
+	r.True(IsErrorTransient(err))
+}
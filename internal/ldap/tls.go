@@ -0,0 +1,104 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// NewTLSConfig builds TLS configuration from TLS_* options of ldaprc.
+//
+// cf. ldap.conf(5)
+func NewTLSConfig(options *koanf.Koanf) (*tls.Config, error) {
+	t := tls.Config{}
+
+	reqcert := strings.ToLower(options.String("TLS_REQCERT"))
+	switch reqcert {
+	case "never", "allow":
+		slog.Debug("Skipping LDAP server certificate verification.", "reqcert", reqcert)
+		t.InsecureSkipVerify = true
+	case "try", "demand", "hard":
+	default:
+		return nil, fmt.Errorf("TLS_REQCERT: bad value: %s", reqcert)
+	}
+
+	cacert := options.String("TLS_CACERT")
+	cacertdir := options.String("TLS_CACERTDIR")
+	if cacert != "" || cacertdir != "" {
+		t.RootCAs = x509.NewCertPool()
+	}
+	if cacert != "" {
+		slog.Debug("Loading LDAP CA certificates.", "path", cacert)
+		pem, err := os.ReadFile(cacert)
+		if err != nil {
+			return nil, fmt.Errorf("TLS_CACERT: %w", err)
+		}
+		if !t.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("TLS_CACERT: %s: no certificate found", cacert)
+		}
+	}
+	if cacertdir != "" {
+		err := loadCACertDir(t.RootCAs, cacertdir)
+		if err != nil {
+			return nil, fmt.Errorf("TLS_CACERTDIR: %w", err)
+		}
+	}
+
+	cert := options.String("TLS_CERT")
+	key := options.String("TLS_KEY")
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, errors.New("TLS_CERT and TLS_KEY must be set together")
+		}
+		slog.Debug("Loading LDAP client certificate.", "cert", cert, "key", key)
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("TLS_CERT: %w", err)
+		}
+		t.Certificates = []tls.Certificate{pair}
+	}
+
+	return &t, nil
+}
+
+func loadCACertDir(pool *x509.CertPool, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// CA directory may contains hash symlinks and other files.
+		if pool.AppendCertsFromPEM(pem) {
+			slog.Debug("Loaded LDAP CA certificate.", "path", path)
+		}
+	}
+	return nil
+}
+
+// configureTLSForURI returns a copy of TLS configuration with server name for
+// certificate verification after StartTLS.
+func configureTLSForURI(t *tls.Config, uri string) (*tls.Config, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	t = t.Clone()
+	t.ServerName = u.Hostname()
+	return t, nil
+}
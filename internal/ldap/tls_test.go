@@ -0,0 +1,41 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSReqCert(t *testing.T) {
+	r := require.New(t)
+	defer k.Delete("TLS_REQCERT")
+
+	for _, value := range []string{"never", "allow"} {
+		_ = k.Set("TLS_REQCERT", value)
+		c, err := NewTLSConfig(k)
+		r.Nil(err, value)
+		r.True(c.InsecureSkipVerify, value)
+	}
+
+	for _, value := range []string{"try", "demand", "hard"} {
+		_ = k.Set("TLS_REQCERT", value)
+		c, err := NewTLSConfig(k)
+		r.Nil(err, value)
+		r.False(c.InsecureSkipVerify, value)
+	}
+
+	_ = k.Set("TLS_REQCERT", "pouet")
+	_, err := NewTLSConfig(k)
+	r.ErrorContains(err, "TLS_REQCERT")
+}
+
+func TestTLSClientCertRequiresKey(t *testing.T) {
+	r := require.New(t)
+	defer k.Delete("TLS_REQCERT")
+	defer k.Delete("TLS_CERT")
+
+	_ = k.Set("TLS_REQCERT", "demand")
+	_ = k.Set("TLS_CERT", "client.crt")
+	_, err := NewTLSConfig(k)
+	r.ErrorContains(err, "TLS_KEY")
+}
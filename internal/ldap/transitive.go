@@ -0,0 +1,146 @@
+package ldap
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"golang.org/x/exp/slices"
+)
+
+// cf. https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-adts/3c5e87db-4728-4f29-b164-01dd7d7391ea
+const (
+	activeDirectoryOID = "1.2.840.113556.1.4.800"
+	inChainOID         = "1.2.840.113556.1.4.1941"
+)
+
+// RootDSE holds server informations read once per connexion.
+type RootDSE struct {
+	ActiveDirectory       bool
+	DefaultNamingContext  string
+	SupportedCapabilities []string
+	read                  bool
+}
+
+// ReadRootDSE inspects server capabilities. Result is cached for the lifetime
+// of the connexion.
+func (c *Client) ReadRootDSE() (*RootDSE, error) {
+	if c.rootDSE == nil {
+		c.rootDSE = &RootDSE{}
+	}
+	if c.rootDSE.read {
+		return c.rootDSE, nil
+	}
+	res, err := c.Search("", ldap3.ScopeBaseObject, "(objectClass=*)", []string{"defaultNamingContext", "supportedCapabilities"}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("root DSE: %w", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, fmt.Errorf("root DSE: not found")
+	}
+	entry := res.Entries[0]
+	dse := c.rootDSE
+	dse.DefaultNamingContext = entry.GetAttributeValue("defaultNamingContext")
+	dse.SupportedCapabilities = entry.GetAttributeValues("supportedCapabilities")
+	dse.ActiveDirectory = slices.Contains(dse.SupportedCapabilities, activeDirectoryOID)
+	dse.read = true
+	slog.Debug("Inspected LDAP root DSE.", "ad", dse.ActiveDirectory, "namingcontext", dse.DefaultNamingContext)
+	return dse, nil
+}
+
+// Object classes of groups. A nested group without members is still a group,
+// not a member.
+var groupClasses = []string{"group", "groupOfNames", "groupOfUniqueNames", "groupOfMembers", "groupOfURLs"}
+
+// SearchTransitiveMembers returns entries of members of group, expanding
+// nested groups.
+//
+// attr references members of group, e.g. member. On Active Directory, uses
+// LDAP_MATCHING_RULE_IN_CHAIN to search all members at once. Otherwise, walks
+// group members level by level with cached and batched base sub-searches.
+// Nested groups are walked but not returned.
+func (p *Pool) SearchTransitiveMembers(group *ldap3.Entry, attr string, s Subsearch) ([]*ldap3.Entry, error) {
+	if strings.EqualFold(attr, "member") {
+		dse, err := p.rootDSE()
+		if err != nil {
+			return nil, err
+		}
+		if dse.ActiveDirectory && dse.DefaultNamingContext != "" {
+			// In-chain matches nested groups too.
+			filter := fmt.Sprintf("(&%s(!%s)(memberOf:%s:=%s))", s.Filter, groupFilter(), inChainOID, ldap3.EscapeFilter(group.DN))
+			res, err := p.Search(dse.DefaultNamingContext, ldap3.ScopeWholeSubtree, filter, s.Attributes, s.PageSize)
+			if err != nil {
+				return nil, err
+			}
+			return res.Entries, nil
+		}
+	}
+
+	// A nested group has members or a group class. A member matches
+	// sub-search filter.
+	walk := Subsearch{
+		Scope:      ldap3.ScopeBaseObject,
+		Filter:     fmt.Sprintf("(|(%s=*)%s)", attr, s.Filter),
+		Attributes: append([]string{attr, "objectClass"}, s.Attributes...),
+		PageSize:   s.PageSize,
+		BatchSize:  s.BatchSize,
+	}
+	seen := mapset.NewSet(NormalizeDN(group.DN))
+	level := group.GetEqualFoldAttributeValues(attr)
+	var entries []*ldap3.Entry
+	for len(level) > 0 {
+		var bases []string
+		for _, dn := range level {
+			if !seen.Add(NormalizeDN(dn)) {
+				slog.Debug("Skipping already walked member.", "group", group.DN, "dn", dn)
+				continue
+			}
+			bases = append(bases, dn)
+		}
+
+		res, errs := p.Subsearch(bases, walk)
+		for _, err := range errs {
+			if ldap3.IsErrorWithCode(err, ldap3.LDAPResultNoSuchObject) {
+				slog.Debug("Skipping missing member.", "group", group.DN, "err", err)
+				continue
+			}
+			return nil, err
+		}
+
+		level = nil
+		for _, entry := range res {
+			members := entry.GetEqualFoldAttributeValues(attr)
+			if len(members) == 0 && !isGroup(entry) {
+				entries = append(entries, entry)
+				continue
+			}
+			slog.Debug("Walking nested group.", "group", group.DN, "dn", entry.DN)
+			level = append(level, members...)
+		}
+	}
+	return entries, nil
+}
+
+// groupFilter matches entries of any group class.
+func groupFilter() string {
+	var b strings.Builder
+	b.WriteString("(|")
+	for _, class := range groupClasses {
+		fmt.Fprintf(&b, "(objectClass=%s)", class)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func isGroup(entry *ldap3.Entry) bool {
+	for _, class := range entry.GetEqualFoldAttributeValues("objectClass") {
+		for _, groupClass := range groupClasses {
+			if strings.EqualFold(class, groupClass) {
+				return true
+			}
+		}
+	}
+	return false
+}
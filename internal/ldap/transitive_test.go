@@ -0,0 +1,103 @@
+package ldap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+var nestedLDIF = `version: 1
+
+dn: dc=acme,dc=tld
+objectClass: dcObject
+dc: acme
+
+dn: cn=all,dc=acme,dc=tld
+objectClass: groupOfNames
+cn: all
+member: CN=Team,dc=acme,dc=tld
+member: cn=alice,dc=acme,dc=tld
+member: cn=empty,dc=acme,dc=tld
+member: cn=gone,dc=acme,dc=tld
+
+dn: cn=team,dc=acme,dc=tld
+objectClass: groupOfNames
+cn: team
+member: cn=all,dc=acme,dc=tld
+member: CN=Alice,dc=acme,dc=tld
+member: cn=bob,dc=acme,dc=tld
+
+dn: cn=empty,dc=acme,dc=tld
+objectClass: groupOfNames
+cn: empty
+
+dn: cn=alice,dc=acme,dc=tld
+objectClass: person
+cn: alice
+
+dn: cn=bob,dc=acme,dc=tld
+objectClass: person
+cn: bob
+`
+
+func TestSearchTransitiveMembers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested.ldif")
+	require.Nil(t, os.WriteFile(path, []byte(nestedLDIF), 0o600))
+	c, err := OpenLDIF(path)
+	require.Nil(t, err)
+
+	for _, batchSize := range []int{0, 10} {
+		r := require.New(t)
+		p := NewPool(c)
+		res, err := p.Search("cn=all,dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", []string{"member"}, nil)
+		r.Nil(err)
+		group := res.Entries[0]
+
+		// Cycle through all, DN case variants, an empty group and a missing
+		// member. empty matches filter but is a group.
+		s := Subsearch{Filter: "(cn=*)", Attributes: []string{"cn"}, BatchSize: batchSize}
+		entries, err := p.SearchTransitiveMembers(group, "member", s)
+		r.Nil(err, "batch size %d", batchSize)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.GetAttributeValue("cn"))
+		}
+		r.ElementsMatch([]string{"alice", "bob"}, names, "batch size %d", batchSize)
+
+		// Walking again hits cache.
+		count := Watch.Count
+		_, err = p.SearchTransitiveMembers(group, "member", s)
+		r.Nil(err)
+		r.Equal(count, Watch.Count)
+	}
+}
+
+func TestSearchTransitiveMembersActiveDirectory(t *testing.T) {
+	for _, capabilities := range [][]string{nil, {activeDirectoryOID}} {
+		r := require.New(t)
+		fake := newFakeConn(t, nestedLDIF)
+		fake.answer("", []string{"defaultNamingContext", "supportedCapabilities"}, &ldap3.SearchResult{Entries: []*ldap3.Entry{
+			ldap3.NewEntry("", map[string][]string{
+				"defaultNamingContext":  {"dc=acme,dc=tld"},
+				"supportedCapabilities": capabilities,
+			}),
+		}})
+		p := NewPool(Client{searcher: fake})
+		res, err := p.Search("cn=all,dc=acme,dc=tld", ldap3.ScopeBaseObject, "(objectClass=*)", []string{"member"}, nil)
+		r.Nil(err)
+
+		// Both in-chain search and walk skip nested groups, including
+		// searched group itself.
+		s := Subsearch{Filter: "(cn=*)", Attributes: []string{"cn"}}
+		entries, err := p.SearchTransitiveMembers(res.Entries[0], "member", s)
+		r.Nil(err, "capabilities %v", capabilities)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.GetAttributeValue("cn"))
+		}
+		r.ElementsMatch([]string{"alice", "bob"}, names, "capabilities %v", capabilities)
+	}
+}
@@ -0,0 +1,113 @@
+package ldap
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// CheckUnexpectedDN applies on_unexpected_dn policy on DN without RDN
+// referenced by expressions.
+//
+// Returns a copy of result without values of DN attributes and sub-entries
+// without referenced RDN. Result is left untouched. keep is false if the DN of
+// entry itself is unexpected.
+func (r Result) CheckUnexpectedDN(expressions []string) (out Result, keep bool, err error) {
+	out = r
+	if r.Entry == nil {
+		return out, true, nil
+	}
+	entry := *r.Entry
+	entry.Attributes = slices.Clone(r.Entry.Attributes)
+	out.Entry = &entry
+	out.SubsearchEntries = maps.Clone(r.SubsearchEntries)
+	for _, expr := range expressions {
+		attr, field, hasField := strings.Cut(expr, ".")
+		if !hasField {
+			// Case {cn}, read from entry DN.
+			if !isKnownRDN(attr) {
+				continue
+			}
+			_, err := ResolveFirstRDN(r.Entry.DN, attr)
+			if err != nil {
+				return out, false, r.handleUnexpectedDN(r.Entry.DN, attr, "dn", err)
+			}
+			continue
+		}
+
+		if !isKnownRDN(field) {
+			continue
+		}
+
+		if subEntries, ok := out.SubsearchEntries[attr]; ok {
+			// Case {member.cn} with {member.sAMAccountName}, read from sub-entry DN.
+			var keptEntries []*ldap3.Entry
+			for _, subEntry := range subEntries {
+				_, err := ResolveFirstRDN(subEntry.DN, field)
+				if err != nil {
+					err = r.handleUnexpectedDN(subEntry.DN, field, attr, err)
+					if err != nil {
+						return out, false, err
+					}
+					continue
+				}
+				keptEntries = append(keptEntries, subEntry)
+			}
+			out.SubsearchEntries[attr] = keptEntries
+			continue
+		}
+
+		if strings.EqualFold(attr, "dn") {
+			// Case {dn.cn}
+			_, err := ResolveFirstRDN(r.Entry.DN, field)
+			if err != nil {
+				return out, false, r.handleUnexpectedDN(r.Entry.DN, field, attr, err)
+			}
+			continue
+		}
+
+		// Case {member.cn}, read from attribute value.
+		for i, attribute := range entry.Attributes {
+			if !strings.EqualFold(attribute.Name, attr) {
+				continue
+			}
+			kept := &ldap3.EntryAttribute{Name: attribute.Name}
+			for j, value := range attribute.Values {
+				_, err := ResolveFirstRDN(value, field)
+				if err != nil {
+					err = r.handleUnexpectedDN(value, field, attr, err)
+					if err != nil {
+						return out, false, err
+					}
+					continue
+				}
+				kept.Values = append(kept.Values, value)
+				if j < len(attribute.ByteValues) {
+					kept.ByteValues = append(kept.ByteValues, attribute.ByteValues[j])
+				}
+			}
+			entry.Attributes[i] = kept
+		}
+	}
+	return out, true, nil
+}
+
+func (r *Result) handleUnexpectedDN(dn, rdn, attr string, err error) error {
+	switch r.OnUnexpectedDN {
+	case "fail":
+		return fmt.Errorf("unexpected DN: %s: %s: %w", attr, dn, err)
+	case "ignore":
+		slog.Debug("Ignoring unexpected DN.", "entry", r.Entry.DN, "attribute", attr, "dn", dn, "rdn", rdn, "err", err)
+	default:
+		slog.Warn("Ignoring unexpected DN.", "entry", r.Entry.DN, "attribute", attr, "dn", dn, "rdn", rdn, "err", err)
+	}
+	return nil
+}
+
+func isKnownRDN(attr string) bool {
+	return slices.Contains(KnownRDNs, strings.ToLower(attr))
+}
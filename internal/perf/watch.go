@@ -1,24 +1,49 @@
 package perf
 
 import (
+	"sync"
 	"time"
 )
 
+// StopWatch accumulates durations. Safe for concurrent use.
 type StopWatch struct {
 	Count int
 	Total time.Duration
+	// Operations avoided, e.g. cache hits.
+	Hits int
+	mu   sync.Mutex
 }
 
 type Timeable func()
 
 func (t *StopWatch) TimeIt(fn Timeable) (duration time.Duration) {
 	start := time.Now()
+	t.mu.Lock()
 	t.Count++
+	t.mu.Unlock()
 	defer func() {
 		duration = time.Since(start)
+		t.mu.Lock()
 		t.Total += duration
+		t.mu.Unlock()
 	}()
 
 	fn()
 	return
 }
+
+// Hit counts an operation avoided by cache.
+func (t *StopWatch) Hit() {
+	t.mu.Lock()
+	t.Hits++
+	t.mu.Unlock()
+}
+
+// Reset counters, e.g. before a new synchronization cycle.
+func (t *StopWatch) Reset() {
+	t.mu.Lock()
+	t.Count = 0
+	t.Total = 0
+	t.Hits = 0
+	t.mu.Unlock()
+}
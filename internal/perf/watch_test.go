@@ -23,3 +23,25 @@ func (suite *Suite) TestStopwatch() {
 	r.Less(backup, t.Total)
 	r.Equal(2, t.Count)
 }
+
+func (suite *Suite) TestStopwatchHit() {
+	r := suite.Require()
+
+	t := perf.StopWatch{}
+	t.Hit()
+	t.Hit()
+	r.Equal(2, t.Hits)
+	r.Equal(0, t.Count)
+}
+
+func (suite *Suite) TestStopwatchReset() {
+	r := suite.Require()
+
+	t := perf.StopWatch{}
+	t.TimeIt(func() {})
+	t.Hit()
+	t.Reset()
+	r.Equal(0, t.Count)
+	r.Equal(0, t.Hits)
+	r.Equal(time.Duration(0), t.Total)
+}
@@ -15,6 +15,7 @@ import (
 var (
 	Watch     perf.StopWatch
 	formatter = FmtQueryRewriter{}
+	redactor  = FmtQueryRewriter{Redact: true}
 )
 
 func Apply(ctx context.Context, diff <-chan SyncQuery, really bool) (count int, err error) {
@@ -38,13 +39,14 @@ func Apply(ctx context.Context, diff <-chan SyncQuery, really bool) (count int,
 		}
 
 		// Rewrite query to log a pasteable query even when in Dry mode.
-		sql, _, _ := formatter.RewriteQuery(ctx, pgConn, query.Query, query.QueryArgs)
-		slog.Debug(prefix + "Execute SQL query:\n" + sql)
+		logSQL, _, _ := redactor.RewriteQuery(ctx, pgConn, query.Query, query.QueryArgs)
+		slog.Debug(prefix + "Execute SQL query:\n" + logSQL)
 
 		if !really {
 			continue
 		}
 
+		sql, _, _ := formatter.RewriteQuery(ctx, pgConn, query.Query, query.QueryArgs)
 		var tag pgconn.CommandTag
 		duration := Watch.TimeIt(func() {
 			_, err = pgConn.Exec(ctx, sql)
@@ -2,9 +2,12 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
+	"github.com/dalibo/ldap2pg/v6/internal/secret"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -12,6 +15,8 @@ import (
 var (
 	globalConn *pgx.Conn
 	globalConf *pgx.ConnConfig
+	// Command printing password, from PGPASSWORD_COMMAND.
+	passwordCommand string
 )
 
 func Configure(dsn string) (err error) {
@@ -19,6 +24,7 @@ func Configure(dsn string) (err error) {
 	if err != nil {
 		return
 	}
+	passwordCommand = os.Getenv("PGPASSWORD_COMMAND")
 	if globalConf.ConnectTimeout == 0 {
 		slog.Debug("Setting default Postgres connection timeout.", "timeout", "5s")
 		globalConf.ConnectTimeout, _ = time.ParseDuration("5s")
@@ -56,6 +62,10 @@ func GetConn(ctx context.Context, database string) (*pgx.Conn, error) {
 		slog.Debug("Opening Postgres global connection.", "database", database)
 		c := globalConf.Copy()
 		c.Database = database
+		err = setPassword(c)
+		if err != nil {
+			return nil, err
+		}
 		globalConn, err = pgx.ConnectConfig(ctx, c)
 		if err != nil {
 			return nil, err
@@ -65,6 +75,20 @@ func GetConn(ctx context.Context, database string) (*pgx.Conn, error) {
 	return globalConn, nil
 }
 
+// setPassword runs PGPASSWORD_COMMAND, if defined, on each connection to get
+// a fresh secret.
+func setPassword(c *pgx.ConnConfig) error {
+	if passwordCommand == "" {
+		return nil
+	}
+	password, err := secret.Run(passwordCommand)
+	if err != nil {
+		return fmt.Errorf("postgres password command: %w", err)
+	}
+	c.Password = password
+	return nil
+}
+
 func CloseConn(ctx context.Context) {
 	if nil == globalConn {
 		return
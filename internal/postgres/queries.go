@@ -28,13 +28,19 @@ func (q SyncQuery) String() string {
 	return q.Description
 }
 
-type FmtQueryRewriter struct{}
+// Secret is a string query argument hidden from logs.
+type Secret string
+
+type FmtQueryRewriter struct {
+	// Render Secret arguments as a mask, for logging.
+	Redact bool
+}
 
 func (q FmtQueryRewriter) RewriteQuery(_ context.Context, conn *pgx.Conn, sql string, args []any) (newSQL string, newArgs []any, err error) {
 	sql = strings.TrimSpace(dedent.Dedent(sql))
 	var fmtArgs []any
 	for _, arg := range args {
-		arg, err = formatArg(conn, arg)
+		arg, err = q.formatArg(conn, arg)
 		if err != nil {
 			return
 		}
@@ -44,10 +50,15 @@ func (q FmtQueryRewriter) RewriteQuery(_ context.Context, conn *pgx.Conn, sql st
 	return
 }
 
-func formatArg(conn *pgx.Conn, arg any) (newArg any, err error) {
+func (q FmtQueryRewriter) formatArg(conn *pgx.Conn, arg any) (newArg any, err error) {
 	switch arg := arg.(type) {
 	case pgx.Identifier:
 		newArg = arg.Sanitize()
+	case Secret:
+		if q.Redact {
+			return "'********'", nil
+		}
+		return q.formatArg(conn, string(arg))
 	case string:
 		s, err := conn.PgConn().EscapeString(arg)
 		if err != nil {
@@ -57,7 +68,7 @@ func formatArg(conn *pgx.Conn, arg any) (newArg any, err error) {
 	case []any:
 		b := strings.Builder{}
 		for _, item := range arg {
-			item, err := formatArg(conn, item)
+			item, err := q.formatArg(conn, item)
 			if err != nil {
 				return newArg, err
 			}
@@ -1,6 +1,7 @@
 package privileges
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -108,8 +109,15 @@ func (r GrantRule) Formats() []pyfmt.Format {
 	return []pyfmt.Format{r.Owner, r.Privilege, r.Database, r.Schema, r.To}
 }
 
-func (r GrantRule) Generate(results *ldap.Result) <-chan Grant {
-	ch := make(chan Grant)
+// GeneratedGrant holds a grant generated from a result or the error of its
+// generation.
+type GeneratedGrant struct {
+	Grant Grant
+	Err   error
+}
+
+func (r GrantRule) Generate(results *ldap.Result) <-chan GeneratedGrant {
+	ch := make(chan GeneratedGrant)
 	go func() {
 		defer close(ch)
 
@@ -121,25 +129,36 @@ func (r GrantRule) Generate(results *ldap.Result) <-chan Grant {
 			close(vchanw)
 			vchan = vchanw
 		} else {
-			vchan = results.GenerateValues(r.Owner, r.Privilege, r.Database, r.Schema, r.To)
+			var err error
+			vchan, err = results.GenerateValues(r.Owner, r.Privilege, r.Database, r.Schema, r.To)
+			if err != nil {
+				ch <- GeneratedGrant{Err: fmt.Errorf("%s: %w", results.Entry.DN, err)}
+				return
+			}
 		}
 
 		for values := range vchan {
-			profile := r.Privilege.Format(values)
+			profile, err := r.Privilege.Render(values)
+			if err != nil {
+				// Keep draining values.
+				ch <- GeneratedGrant{Err: fmt.Errorf("%s: %w", results.Entry.DN, err)}
+				continue
+			}
 			for _, priv := range profiles[profile] {
 				acl := acls[priv.ACL()]
+				var errs [4]error
 				grant := Grant{
-					ACL:     priv.On,
-					Grantee: r.To.Format(values),
-					Type:    priv.Type,
+					ACL:  priv.On,
+					Type: priv.Type,
 				}
+				grant.Grantee, errs[0] = r.To.Render(values)
 
 				if acl.Uses("owner") {
-					grant.Owner = r.Owner.Format(values)
+					grant.Owner, errs[1] = r.Owner.Render(values)
 				}
 
 				if acl.Uses("schema") {
-					grant.Schema = r.Schema.Format(values)
+					grant.Schema, errs[2] = r.Schema.Render(values)
 				}
 
 				if acl.Uses("object") {
@@ -147,10 +166,15 @@ func (r GrantRule) Generate(results *ldap.Result) <-chan Grant {
 				}
 
 				if acl.Scope != "instance" || acl.Uses("database") {
-					grant.Database = r.Database.Format(values)
+					grant.Database, errs[3] = r.Database.Render(values)
 				}
 
-				ch <- grant
+				err := errors.Join(errs[:]...)
+				if err != nil {
+					ch <- GeneratedGrant{Err: fmt.Errorf("%s: %w", results.Entry.DN, err)}
+					continue
+				}
+				ch <- GeneratedGrant{Grant: grant}
 			}
 		}
 	}()
@@ -0,0 +1,75 @@
+package pyfmt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formatGUID renders a Microsoft GUID like objectGUID. First three groups are
+// little-endian. Empty value, like a missing attribute, renders empty.
+//
+// cf. https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-dtyp/001eec5a-7f8b-4293-9e21-ca349392db40
+func formatGUID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", nil
+	}
+	if len(b) != 16 {
+		return "", fmt.Errorf("guid(): %d bytes, expected 16", len(b))
+	}
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10],
+		b[10:16],
+	), nil
+}
+
+// formatSID renders a binary security identifier like objectSid in string
+// form, e.g. S-1-5-21-1004336348-1177238915-682003330-512. Empty value renders
+// empty.
+//
+// cf. https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-dtyp/f992ad60-0fe4-4b87-9fed-beb478836861
+func formatSID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", nil
+	}
+	if len(b) < 8 {
+		return "", fmt.Errorf("sid(): %d bytes, expected at least 8", len(b))
+	}
+	count := int(b[1])
+	if len(b) != 8+4*count {
+		return "", fmt.Errorf("sid(): %d bytes, expected %d for %d sub-authorities", len(b), 8+4*count, count)
+	}
+	// Identifier authority is a 48-bit big-endian integer.
+	var authority uint64
+	for _, c := range b[2:8] {
+		authority = authority<<8 | uint64(c)
+	}
+	var s strings.Builder
+	fmt.Fprintf(&s, "S-%d-%d", b[0], authority)
+	for i := range count {
+		fmt.Fprintf(&s, "-%d", binary.LittleEndian.Uint32(b[8+4*i:]))
+	}
+	return s.String(), nil
+}
+
+// formatFlag renders true if integer v has any bit of mask set, like
+// userAccountControl flags. Otherwise false. Empty value renders empty.
+//
+// cf. https://learn.microsoft.com/en-us/troubleshoot/windows-server/active-directory/useraccountcontrol-manipulate-account-properties
+func formatFlag(v string, mask int) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("flag(): %q: expected integer", v)
+	}
+	if n&int64(mask) != 0 {
+		return "true", nil
+	}
+	return "false", nil
+}
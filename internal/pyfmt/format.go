@@ -23,7 +23,8 @@ type Field struct {
 	FieldName  string
 	FormatSpec string
 	Conversion string
-	Method     string
+	// Method chain, e.g. lower() or split('@', 0).lower().
+	Methods []Method
 }
 
 func Parse(f string) (format Format, err error) {
@@ -43,13 +44,17 @@ func (f *Format) Parse(s string) (err error) {
 	for i := 0; i < end; { // Loops sections in s.
 		start = i // Track the start of the section. i will move to the end.
 		if inField {
-			loc := strings.IndexByte(s[i:], '}')
+			loc := indexOutsideArgs(s[i:], "}")
 			if loc == -1 {
 				err = fmt.Errorf("end of string before end of field")
 				i = end // End loop at end of step.
 			} else {
 				i += loc // Move before }
-				field := parseField(s[start:i])
+				var field Field
+				field, err = parseField(s[start:i])
+				if err != nil {
+					return fmt.Errorf("%s: %w", s, err)
+				}
 				f.Sections = append(f.Sections, &field)
 				f.Fields = append(f.Fields, &field)
 				i++ // Move after }
@@ -94,33 +99,49 @@ func (f *Format) Parse(s string) (err error) {
 	return
 }
 
-func parseField(s string) (f Field) {
-	before, after, found := strings.Cut(s, "!")
-	if found {
+func parseField(s string) (f Field, err error) {
+	// Methods arguments may contain ! or :.
+	loc := indexOutsideArgs(s, "!:")
+	expr, after := s, ""
+	if loc != -1 {
+		expr, after = s[:loc], s[loc:]
+	}
+	if rest, found := strings.CutPrefix(after, "!"); found {
 		// case {0!r} OR {0!r:>30}
-		f.FieldName = before
-		before, after, _ = strings.Cut(after, ":")
-		f.Conversion = before
+		f.Conversion, f.FormatSpec, _ = strings.Cut(rest, ":")
 	} else {
 		// case {0} OR {0:>30}
-		before, after, _ = strings.Cut(before, ":")
-		f.FieldName = before
+		f.FormatSpec = strings.TrimPrefix(after, ":")
 	}
-	f.FormatSpec = after
-	if strings.HasSuffix(f.FieldName, "()") {
-		lastPoint := strings.LastIndex(f.FieldName, ".")
-		f.Method = f.FieldName[lastPoint+1:]
-		f.FieldName = f.FieldName[:lastPoint]
+	f.FieldName, f.Methods, err = parseExpression(expr)
+	if err != nil {
+		return
+	}
+	if strings.HasPrefix(after, "!") {
+		err = checkConversion(f.Conversion)
+		if err != nil {
+			return
+		}
 	}
+	_, err = ParseSpec(f.FormatSpec)
 	return
 }
 
+// Format renders values. A value rejected by a method renders empty. Use
+// Render to fail on such value.
 func (f Format) Format(values map[string]string) string {
+	s, _ := f.Render(values)
+	return s
+}
+
+// Render renders values. Fails if a method rejects a value, e.g. guid() on a
+// value of wrong length.
+func (f Format) Render(values map[string]string) (string, error) {
 	if values == nil {
 		if !f.IsStatic() {
 			panic("rendering dynamic format without values")
 		}
-		return f.String()
+		return f.String(), nil
 	}
 
 	b := strings.Builder{}
@@ -132,23 +153,21 @@ func (f Format) Format(values map[string]string) string {
 		} else {
 			f := item.(*Field)
 			v := values[f.FieldName]
-			switch f.Method {
-			case "":
-			case "lower()":
-				v = strings.ToLower(v)
-			case "upper()":
-				v = strings.ToUpper(v)
-			case "identifier()":
-				v = fmt.Sprintf("\"%s\"", v)
-			case "string()":
-				v = fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''"))
-			default:
-				v = "!INVALID_METHOD"
+			for _, m := range f.Methods {
+				var err error
+				v, err = m.Apply(v)
+				if err != nil {
+					return "", fmt.Errorf("%s: %w", f.FieldName, err)
+				}
 			}
+			v = convert(f.Conversion, v)
+			// Spec is validated by Parse.
+			spec, _ := ParseSpec(f.FormatSpec)
+			v = spec.Apply(v)
 			b.WriteString(v)
 		}
 	}
-	return b.String()
+	return b.String(), nil
 }
 
 func (f Format) String() string {
@@ -29,7 +29,8 @@ func (suite *Suite) TestParseMethod() {
 	r.Equal(1, len(f.Fields))
 	r.Equal(1, len(f.Sections))
 	r.Equal("member.cn", f.Fields[0].FieldName)
-	r.Equal("lower()", f.Fields[0].Method)
+	r.Len(f.Fields[0].Methods, 1)
+	r.Equal("lower", f.Fields[0].Methods[0].Name)
 }
 
 func (suite *Suite) TestParseFieldOnly() {
@@ -120,3 +121,217 @@ func Test(t *testing.T) {
 	}
 	suite.Run(t, new(Suite))
 }
+
+func (suite *Suite) TestFormatBinary() {
+	r := suite.Require()
+
+	f, err := pyfmt.Parse("{objectGUID.guid()} {objectSid.sid()} {objectGUID.hex()} {objectGUID.base64()}")
+	r.Nil(err)
+
+	guid := string([]byte{
+		0x4f, 0x9a, 0x2b, 0x10, 0x3c, 0x5e, 0x7d, 0x4a,
+		0x8b, 0x1c, 0x0d, 0x2e, 0x3f, 0x40, 0x51, 0x62,
+	})
+	sid := string([]byte{
+		0x01, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05,
+		0x15, 0x00, 0x00, 0x00,
+		0xdc, 0xf4, 0xdc, 0x3b,
+		0x83, 0x3d, 0x2b, 0x46,
+		0x82, 0x8b, 0xa6, 0x28,
+		0x00, 0x02, 0x00, 0x00,
+	})
+	s := f.Format(map[string]string{
+		"objectGUID": guid,
+		"objectSid":  sid,
+	})
+	r.Equal("102b9a4f-5e3c-4a7d-8b1c-0d2e3f405162 "+
+		"S-1-5-21-1004336348-1177238915-682003330-512 "+
+		"4f9a2b103c5e7d4a8b1c0d2e3f405162 "+
+		"T5orEDxefUqLHA0uP0BRYg==", s)
+
+	_, err = f.Render(map[string]string{
+		"objectGUID": "short",
+		"objectSid":  sid,
+	})
+	r.ErrorContains(err, "objectGUID: guid(): 5 bytes, expected 16")
+	_, err = f.Render(map[string]string{
+		"objectGUID": guid,
+		"objectSid":  sid[:12],
+	})
+	r.ErrorContains(err, "objectSid: sid(): 12 bytes, expected 28 for 5 sub-authorities")
+	// Missing attributes.
+	r.Equal("   ", f.Format(map[string]string{}))
+}
+
+func (suite *Suite) TestFormatExpiration() {
+	r := suite.Require()
+
+	f, err := pyfmt.Parse("{accountExpires.filetime()} {shadowExpire.epochdays()}")
+	r.Nil(err)
+
+	r.Equal("2025-12-31T00:00:00Z 2025-12-31T00:00:00Z", f.Format(map[string]string{
+		"accountExpires": "134116128000000000",
+		"shadowExpire":   "20453",
+	}))
+	r.Equal("infinity infinity", f.Format(map[string]string{
+		"accountExpires": "9223372036854775807",
+		"shadowExpire":   "-1",
+	}))
+	_, err = f.Render(map[string]string{
+		"accountExpires": "0",
+		"shadowExpire":   "never",
+	})
+	r.ErrorContains(err, `epochdays(): "never": expected integer from -1`)
+	r.Equal(" ", f.Format(map[string]string{}))
+	_, err = pyfmt.Method{Name: "filetime"}.Apply("-3")
+	r.ErrorContains(err, `filetime(): "-3": expected positive integer`)
+}
+
+func (suite *Suite) TestFormatFlag() {
+	r := suite.Require()
+
+	f, err := pyfmt.Parse("{userAccountControl.flag(2)}")
+	r.Nil(err)
+	r.Equal("true", f.Format(map[string]string{"userAccountControl": "514"}))
+	r.Equal("false", f.Format(map[string]string{"userAccountControl": "512"}))
+	_, err = f.Render(map[string]string{"userAccountControl": "x"})
+	r.ErrorContains(err, `flag(): "x": expected integer`)
+	r.Equal("", f.Format(map[string]string{}))
+
+	_, err = pyfmt.Parse("{userAccountControl.flag(0)}")
+	r.ErrorContains(err, "mask must be positive")
+}
+
+func (suite *Suite) TestParseMethodChain() {
+	r := suite.Require()
+
+	f, err := pyfmt.Parse("{mail.split('@', 0).replace('.', '_').lower()}")
+	r.Nil(err)
+	r.Equal(1, len(f.Fields))
+	r.Equal("mail", f.Fields[0].FieldName)
+	r.Len(f.Fields[0].Methods, 3)
+	r.Equal("split", f.Fields[0].Methods[0].Name)
+	r.Equal([]any{"@", 0}, f.Fields[0].Methods[0].Args)
+	r.Equal("john_doe", f.Format(map[string]string{"mail": "John.Doe@corp"}))
+}
+
+func (suite *Suite) TestParseMethodArgumentSpecialChars() {
+	r := suite.Require()
+
+	f, err := pyfmt.Parse(`{cn.regex('^app_([\w:]{2,})$', 1).replace(":", "!")}_{cn.replace('\'', "}")}`)
+	r.Nil(err)
+	r.Equal(2, len(f.Fields))
+	r.Equal("", f.Fields[0].Conversion)
+	r.Equal("", f.Fields[0].FormatSpec)
+	r.Equal("ab!c_app_ab:c", f.Format(map[string]string{"cn": "app_ab:c"}))
+	r.Equal("_}x", f.Format(map[string]string{"cn": "'x"}))
+}
+
+func (suite *Suite) TestFormatMethods() {
+	r := suite.Require()
+
+	for _, c := range []struct {
+		format string
+		value  string
+		want   string
+	}{
+		{"{v.trim()}", "  alice \n", "alice"},
+		{"{v.trim('_')}", "__alice__", "alice"},
+		{"{v.slug()}", "Élodie Martin-Dupont", "elodie_martin_dupont"},
+		{"{v.truncate(3)}", "élodie", "élo"},
+		{"{v.truncate(63)}", "alice", "alice"},
+		{"{v.regex('^app_(.*)$', 1)}", "app_sales", "sales"},
+		{"{v.regex('^app_')}", "app_sales", "app_"},
+		{"{v.regex('^app_(.*)$', 1).default('none')}", "sales", "none"},
+		{"{v.split('@', 1)}", "alice@acme", "acme"},
+		{"{v.split('.', -1)}", "a.b.c", "c"},
+		{"{v.split('@', 2)}", "alice@acme", ""},
+		{"{v.default('x')}", "alice", "alice"},
+		{"{v.default('x')}", "", "x"},
+		{"{v.upper().identifier()}", "alice", `"ALICE"`},
+	} {
+		f, err := pyfmt.Parse(c.format)
+		r.Nil(err, c.format)
+		r.Equal(c.want, f.Format(map[string]string{"v": c.value}), c.format)
+	}
+}
+
+func (suite *Suite) TestParseMethodErrors() {
+	r := suite.Require()
+
+	for format, msg := range map[string]string{
+		"{cn.capitalize()}":        "unknown method capitalize()",
+		"{cn.replace('-')}":        "replace() takes 2 arguments, got 1",
+		"{cn.regex()}":             "regex() takes 1 to 2 arguments, got 0",
+		"{cn.truncate('63')}":      "truncate() argument 1 must be an integer",
+		"{cn.split(0, '@')}":       "split() argument 1 must be a string",
+		"{cn.regex('(')}":          "regex(): error parsing regexp",
+		"{cn.regex('a(b)', 2)}":    "regex(): no group 2",
+		"{cn.split('', 0)}":        "split(): empty separator",
+		"{cn.truncate(-1)}":        "truncate(): negative length",
+		"{cn.replace('a' 'b')}":    "expected ',' or ')'",
+		"{cn.replace('a', 'b'}":    "unexpected end of format",
+		"{cn.replace('a, 'b')}":    "unexpected end of format",
+		"{cn.lower().upper}":       "expected '('",
+		"{lower()}":                "method without field",
+		"{cn.replace('a', bogus)}": "bad argument",
+		"{cn.lower()x}":            "expected '.'",
+		"{cn.default('x').lower(}": "unexpected end of format",
+	} {
+		_, err := pyfmt.Parse(format)
+		r.ErrorContains(err, msg, format)
+	}
+}
+
+func (suite *Suite) TestFormatSpec() {
+	r := suite.Require()
+
+	for _, c := range []struct {
+		format string
+		value  string
+		want   string
+	}{
+		{"{cn:.3}", "alice", "ali"},
+		{"{cn:.20}", "alice", "alice"},
+		{"{cn:>8}", "alice", "   alice"},
+		{"{cn:8}", "alice", "alice   "},
+		{"{cn:*^9}", "alice", "**alice**"},
+		{"{cn:*^8}", "alice", "*alice**"},
+		{"{cn:05}", "ab", "ab000"},
+		{"{cn:<6.2s}", "alice", "al    "},
+		{"{cn:é>4}", "é", "éééé"},
+		{"{cn.upper():.3}", "alice", "ALI"},
+		{"{cn!s}", "alice", "alice"},
+		{"{cn!r}", "alice", "'alice'"},
+		{"{cn!r}", "l'équipe", `"l'équipe"`},
+		{"{cn!r}", `a'b"c\`, `'a\'b"c\\'`},
+		{"{cn!r}", "a\tb\x01", `'a\tb\x01'`},
+		{"{cn!a}", "élodie", `'\xe9lodie'`},
+		{"{cn!a}", "日本", `'\u65e5\u672c'`},
+		{"{cn!r}", "日本", `'日本'`},
+		{"{cn!r:>10}", "bob", "     'bob'"},
+	} {
+		f, err := pyfmt.Parse(c.format)
+		r.Nil(err, c.format)
+		r.Equal(c.want, f.Format(map[string]string{"cn": c.value}), c.format)
+	}
+}
+
+func (suite *Suite) TestParseSpecErrors() {
+	r := suite.Require()
+
+	for format, msg := range map[string]string{
+		"{cn:+}":   "sign not allowed",
+		"{cn:=5}":  "'=' alignment not allowed",
+		"{cn:#}":   "alternate form (#) not allowed",
+		"{cn:,}":   "cannot specify ','",
+		"{cn:.}":   "missing precision",
+		"{cn:d}":   "unknown format code 'd'",
+		"{cn:5x5}": "invalid format specifier",
+		"{cn!x}":   "unknown conversion specifier x",
+		"{cn!}":    "missing conversion specifier",
+	} {
+		_, err := pyfmt.Parse(format)
+		r.ErrorContains(err, msg, format)
+	}
+}
@@ -0,0 +1,346 @@
+package pyfmt
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gosimple/slug"
+)
+
+// Method transforms the value of a field, e.g. lower() or replace('-', '_').
+type Method struct {
+	Name string
+	// Arguments are either string or int.
+	Args []any
+	re   *regexp.Regexp
+}
+
+type argKind int
+
+const (
+	stringArg argKind = iota
+	intArg
+)
+
+// signatures lists arguments of methods. Arguments after required ones are
+// optional.
+var signatures = map[string]struct {
+	args     []argKind
+	required int
+}{
+	"base64":     {},
+	"default":    {args: []argKind{stringArg}, required: 1},
+	"epochdays":  {},
+	"filetime":   {},
+	"flag":       {args: []argKind{intArg}, required: 1},
+	"guid":       {},
+	"hex":        {},
+	"identifier": {},
+	"lower":      {},
+	"regex":      {args: []argKind{stringArg, intArg}, required: 1},
+	"replace":    {args: []argKind{stringArg, stringArg}, required: 2},
+	"sid":        {},
+	"slug":       {},
+	"split":      {args: []argKind{stringArg, intArg}, required: 2},
+	"string":     {},
+	"trim":       {args: []argKind{stringArg}},
+	"truncate":   {args: []argKind{intArg}, required: 1},
+	"upper":      {},
+}
+
+// check validates method name and arguments.
+func (m *Method) check() error {
+	sig, ok := signatures[m.Name]
+	if !ok {
+		return fmt.Errorf("unknown method %s()", m.Name)
+	}
+	if len(m.Args) < sig.required || len(m.Args) > len(sig.args) {
+		if sig.required == len(sig.args) {
+			return fmt.Errorf("%s() takes %d arguments, got %d", m.Name, len(sig.args), len(m.Args))
+		}
+		return fmt.Errorf("%s() takes %d to %d arguments, got %d", m.Name, sig.required, len(sig.args), len(m.Args))
+	}
+	for i, arg := range m.Args {
+		switch sig.args[i] {
+		case stringArg:
+			if _, ok := arg.(string); !ok {
+				return fmt.Errorf("%s() argument %d must be a string", m.Name, i+1)
+			}
+		case intArg:
+			if _, ok := arg.(int); !ok {
+				return fmt.Errorf("%s() argument %d must be an integer", m.Name, i+1)
+			}
+		}
+	}
+
+	switch m.Name {
+	case "regex":
+		var err error
+		m.re, err = regexp.Compile(m.Args[0].(string))
+		if err != nil {
+			return fmt.Errorf("regex(): %w", err)
+		}
+		if len(m.Args) > 1 && (m.Args[1].(int) < 0 || m.Args[1].(int) > m.re.NumSubexp()) {
+			return fmt.Errorf("regex(): no group %d", m.Args[1])
+		}
+	case "flag":
+		if m.Args[0].(int) <= 0 {
+			return fmt.Errorf("flag(): mask must be positive")
+		}
+	case "split":
+		if m.Args[0].(string) == "" {
+			return fmt.Errorf("split(): empty separator")
+		}
+	case "truncate":
+		if m.Args[0].(int) < 0 {
+			return fmt.Errorf("truncate(): negative length")
+		}
+	}
+	return nil
+}
+
+// Apply transforms v. Fails if v is not valid for method, e.g. a binary GUID
+// of wrong length.
+func (m Method) Apply(v string) (string, error) {
+	switch m.Name {
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(v)), nil
+	case "default":
+		if v == "" {
+			return m.Args[0].(string), nil
+		}
+		return v, nil
+	case "epochdays":
+		return formatEpochDays(v)
+	case "filetime":
+		return formatFiletime(v)
+	case "flag":
+		return formatFlag(v, m.Args[0].(int))
+	case "guid":
+		return formatGUID([]byte(v))
+	case "hex":
+		return hex.EncodeToString([]byte(v)), nil
+	case "identifier":
+		return fmt.Sprintf("\"%s\"", v), nil
+	case "lower":
+		return strings.ToLower(v), nil
+	case "regex":
+		group := 0
+		if len(m.Args) > 1 {
+			group = m.Args[1].(int)
+		}
+		match := m.re.FindStringSubmatch(v)
+		if match == nil {
+			return "", nil
+		}
+		return match[group], nil
+	case "replace":
+		return strings.ReplaceAll(v, m.Args[0].(string), m.Args[1].(string)), nil
+	case "sid":
+		return formatSID([]byte(v))
+	case "slug":
+		return strings.ReplaceAll(slug.Make(v), "-", "_"), nil
+	case "split":
+		parts := strings.Split(v, m.Args[0].(string))
+		i := m.Args[1].(int)
+		if i < 0 {
+			// Python-like negative index.
+			i += len(parts)
+		}
+		if i < 0 || i >= len(parts) {
+			return "", nil
+		}
+		return parts[i], nil
+	case "string":
+		return fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "''")), nil
+	case "trim":
+		if len(m.Args) > 0 {
+			return strings.Trim(v, m.Args[0].(string)), nil
+		}
+		return strings.TrimSpace(v), nil
+	case "truncate":
+		n := m.Args[0].(int)
+		runes := []rune(v)
+		if len(runes) > n {
+			return string(runes[:n]), nil
+		}
+		return v, nil
+	case "upper":
+		return strings.ToUpper(v), nil
+	default:
+		return "", fmt.Errorf("unknown method %s()", m.Name)
+	}
+}
+
+// parseExpression splits field name from method chain, e.g.
+// mail.split('@', 0).lower() gives mail and methods split and lower.
+func parseExpression(s string) (name string, methods []Method, err error) {
+	open := strings.IndexByte(s, '(')
+	if open == -1 {
+		return s, nil, nil
+	}
+	dot := strings.LastIndexByte(s[:open], '.')
+	if dot == -1 {
+		return "", nil, fmt.Errorf("method without field: %s", s)
+	}
+	name = s[:dot]
+	p := methodParser{s: s, i: dot}
+	for !p.done() {
+		var m Method
+		m, err = p.method()
+		if err != nil {
+			return "", nil, err
+		}
+		err = m.check()
+		if err != nil {
+			return "", nil, err
+		}
+		methods = append(methods, m)
+	}
+	return
+}
+
+type methodParser struct {
+	s string
+	i int
+}
+
+func (p *methodParser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *methodParser) skipSpaces() {
+	for !p.done() && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+func (p *methodParser) expect(c byte) error {
+	p.skipSpaces()
+	if p.done() || p.s[p.i] != c {
+		return fmt.Errorf("expected %q at %d in %s", c, p.i, p.s)
+	}
+	p.i++
+	return nil
+}
+
+// method parses .name(args...)
+func (p *methodParser) method() (m Method, err error) {
+	err = p.expect('.')
+	if err != nil {
+		return
+	}
+	start := p.i
+	for !p.done() && (unicode.IsLetter(rune(p.s[p.i])) || unicode.IsDigit(rune(p.s[p.i])) || p.s[p.i] == '_') {
+		p.i++
+	}
+	m.Name = p.s[start:p.i]
+	if m.Name == "" {
+		return m, fmt.Errorf("missing method name at %d in %s", start, p.s)
+	}
+	err = p.expect('(')
+	if err != nil {
+		return
+	}
+	p.skipSpaces()
+	if !p.done() && p.s[p.i] == ')' {
+		p.i++
+		return
+	}
+	for {
+		var arg any
+		arg, err = p.argument()
+		if err != nil {
+			return
+		}
+		m.Args = append(m.Args, arg)
+		p.skipSpaces()
+		if p.done() {
+			return m, fmt.Errorf("unterminated arguments of %s()", m.Name)
+		}
+		c := p.s[p.i]
+		p.i++
+		if c == ')' {
+			return
+		}
+		if c != ',' {
+			return m, fmt.Errorf("expected ',' or ')' at %d in %s", p.i-1, p.s)
+		}
+	}
+}
+
+// argument parses a quoted string or an integer.
+func (p *methodParser) argument() (any, error) {
+	p.skipSpaces()
+	if p.done() {
+		return nil, fmt.Errorf("missing argument in %s", p.s)
+	}
+	c := p.s[p.i]
+	if c == '\'' || c == '"' {
+		return p.quoted(c)
+	}
+	start := p.i
+	if c == '-' {
+		p.i++
+	}
+	for !p.done() && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+		p.i++
+	}
+	n, err := strconv.Atoi(p.s[start:p.i])
+	if err != nil {
+		return nil, fmt.Errorf("bad argument at %d in %s", start, p.s)
+	}
+	return n, nil
+}
+
+// quoted parses a string literal. Backslash escapes quotes and backslash.
+// Other backslashes are kept, e.g. for regular expressions.
+func (p *methodParser) quoted(quote byte) (string, error) {
+	p.i++ // Skip opening quote.
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && !p.done() && (p.s[p.i] == quote || p.s[p.i] == '\\'):
+			b.WriteByte(p.s[p.i])
+			p.i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string in %s", p.s)
+}
+
+// indexOutsideArgs returns the index of the first byte of chars in s,
+// ignoring bytes in method arguments. Returns -1 if not found.
+func indexOutsideArgs(s, chars string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case depth > 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte(chars, c) != -1:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,168 @@
+package pyfmt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Spec is a parsed format spec for strings: [[fill]align][0][width][.precision][s].
+//
+// cf. https://docs.python.org/3/library/string.html#format-specification-mini-language
+type Spec struct {
+	Fill  rune
+	Align byte
+	Width int
+	// Maximum number of characters. -1 means no truncation.
+	Precision int
+}
+
+// ParseSpec parses format spec like Python does for str.
+func ParseSpec(s string) (spec Spec, err error) {
+	spec = Spec{Fill: ' ', Align: '<', Precision: -1}
+	if s == "" {
+		return
+	}
+	rest := s
+	fill, size := utf8.DecodeRuneInString(rest)
+	if len(rest) > size && strings.IndexByte("<>=^", rest[size]) != -1 {
+		spec.Fill = fill
+		spec.Align = rest[size]
+		rest = rest[size+1:]
+	} else if strings.IndexByte("<>=^", rest[0]) != -1 {
+		spec.Align = rest[0]
+		rest = rest[1:]
+	} else if rest[0] == '0' {
+		// Zero padding without explicit alignment.
+		spec.Fill = '0'
+	}
+	if spec.Align == '=' {
+		return spec, errors.New("'=' alignment not allowed in string format specifier")
+	}
+	if rest != "" && strings.IndexByte("+- ", rest[0]) != -1 {
+		return spec, errors.New("sign not allowed in string format specifier")
+	}
+	if rest != "" && rest[0] == '#' {
+		return spec, errors.New("alternate form (#) not allowed in string format specifier")
+	}
+
+	var digits string
+	digits, rest = cutDigits(rest)
+	if digits != "" {
+		_, _ = fmt.Sscan(digits, &spec.Width)
+	}
+	if rest != "" && (rest[0] == ',' || rest[0] == '_') {
+		return spec, fmt.Errorf("cannot specify '%c' with 's'", rest[0])
+	}
+	if after, found := strings.CutPrefix(rest, "."); found {
+		digits, rest = cutDigits(after)
+		if digits == "" {
+			return spec, errors.New("format specifier missing precision")
+		}
+		_, _ = fmt.Sscan(digits, &spec.Precision)
+	}
+	switch rest {
+	case "", "s":
+	default:
+		if utf8.RuneCountInString(rest) == 1 {
+			return spec, fmt.Errorf("unknown format code '%s' for object of type 'str'", rest)
+		}
+		return spec, fmt.Errorf("invalid format specifier '%s'", s)
+	}
+	return
+}
+
+func cutDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// Apply truncates and pads v.
+func (spec Spec) Apply(v string) string {
+	runes := []rune(v)
+	if spec.Precision >= 0 && len(runes) > spec.Precision {
+		runes = runes[:spec.Precision]
+	}
+	pad := spec.Width - len(runes)
+	if pad <= 0 {
+		return string(runes)
+	}
+	var left, right int
+	switch spec.Align {
+	case '>':
+		left = pad
+	case '^':
+		left = pad / 2
+		right = pad - left
+	default:
+		right = pad
+	}
+	fill := string(spec.Fill)
+	return strings.Repeat(fill, left) + string(runes) + strings.Repeat(fill, right)
+}
+
+func checkConversion(conversion string) error {
+	switch conversion {
+	case "r", "s", "a":
+		return nil
+	case "":
+		return errors.New("missing conversion specifier")
+	default:
+		return fmt.Errorf("unknown conversion specifier %s", conversion)
+	}
+}
+
+// convert v like Python str(), repr() and ascii().
+func convert(conversion, v string) string {
+	switch conversion {
+	case "r":
+		return repr(v, false)
+	case "a":
+		return repr(v, true)
+	default:
+		return v
+	}
+}
+
+// repr quotes s like Python repr(). If ascii is true, escapes non-ASCII
+// characters like Python ascii().
+func repr(s string, ascii bool) string {
+	quote := '\''
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+	var b strings.Builder
+	b.WriteRune(quote)
+	for _, r := range s {
+		switch {
+		case r == quote || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r < 0x7f:
+			b.WriteRune(r)
+		case !ascii && unicode.IsPrint(r):
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r <= 0xffff:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(&b, `\U%08x`, r)
+		}
+	}
+	b.WriteRune(quote)
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package pyfmt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// timestampLayout renders timestamps in UTC, accepted by Postgres.
+const timestampLayout = "2006-01-02T15:04:05Z"
+
+// Seconds between 1601-01-01 and 1970-01-01.
+const filetimeEpochOffset = 11644473600
+
+// formatFiletime renders a Windows FILETIME like accountExpires: 100
+// nanoseconds intervals since 1601-01-01 UTC. 0 and max int64 mean never.
+// Empty value, like a missing attribute, renders empty.
+//
+// cf. https://learn.microsoft.com/en-us/windows/win32/adschema/a-accountexpires
+func formatFiletime(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("filetime(): %q: expected positive integer", v)
+	}
+	if n == 0 || n == math.MaxInt64 {
+		return "infinity", nil
+	}
+	seconds := n/10_000_000 - filetimeEpochOffset
+	return time.Unix(seconds, 0).UTC().Format(timestampLayout), nil
+}
+
+// formatEpochDays renders a number of days since 1970-01-01 like
+// shadowExpire. -1 means never. Empty value renders empty.
+//
+// cf. shadow(5)
+func formatEpochDays(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || n < -1 {
+		return "", fmt.Errorf("epochdays(): %q: expected integer from -1", v)
+	}
+	if n == -1 {
+		return "infinity", nil
+	}
+	return time.Unix(n*86400, 0).UTC().Format(timestampLayout), nil
+}
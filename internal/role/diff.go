@@ -10,6 +10,12 @@ func Diff(all, managed, wanted Map, fallbackOwner string) <-chan postgres.SyncQu
 	ch := make(chan postgres.SyncQuery)
 	go func() {
 		defer close(ch)
+		renames := wanted.Renames(all, managed)
+		renamed := make(map[string]string)
+		for old, name := range renames {
+			renamed[name] = old
+		}
+
 		// Create missing roles.
 		for _, name := range wanted.Flatten() {
 			role := wanted[name]
@@ -18,6 +24,14 @@ func Diff(all, managed, wanted Map, fallbackOwner string) <-chan postgres.SyncQu
 				if _, ok := managed[name]; !ok {
 					slog.Warn("Reusing unmanaged role. Ensure managed_roles_query returns all wanted roles.", "role", name)
 				}
+				other.renameParents(renames)
+				sendQueries(other.Alter(role), ch)
+			} else if old, ok := renamed[name]; ok {
+				// Rename instead of drop and create to keep objects
+				// and privileges.
+				other := all[old]
+				ch <- other.Rename(name)
+				other.renameParents(renames)
 				sendQueries(other.Alter(role), ch)
 			} else {
 				sendQueries(role.Create(), ch)
@@ -30,6 +44,9 @@ func Diff(all, managed, wanted Map, fallbackOwner string) <-chan postgres.SyncQu
 			if _, ok := wanted[name]; ok {
 				continue
 			}
+			if _, ok := renames[name]; ok {
+				continue
+			}
 
 			if name == "public" {
 				continue
@@ -0,0 +1,52 @@
+package role_test
+
+import (
+	"testing"
+
+	"github.com/dalibo/ldap2pg/v6/internal/role"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenames(t *testing.T) {
+	r := require.New(t)
+
+	all := role.Map{
+		"jdoe":    role.Role{Name: "jdoe", Key: "k1"},
+		"kept":    role.Role{Name: "kept", Key: "k2"},
+		"dup1":    role.Role{Name: "dup1", Key: "k3"},
+		"dup2":    role.Role{Name: "dup2", Key: "k3"},
+		"unkeyed": role.Role{Name: "unkeyed"},
+		"dropped": role.Role{Name: "dropped", Key: "k5"},
+	}
+	wanted := role.Map{
+		"jsmith":  role.Role{Name: "jsmith", Key: "k1"},
+		"kept":    role.Role{Name: "kept", Key: "k2"},
+		"kept2":   role.Role{Name: "kept2", Key: "k2"},
+		"dup":     role.Role{Name: "dup", Key: "k3"},
+		"created": role.Role{Name: "created", Key: "k4"},
+		// Two wanted roles claim the key of dropped.
+		"new1": role.Role{Name: "new1", Key: "k5"},
+		"new2": role.Role{Name: "new2", Key: "k5"},
+	}
+	r.Equal(map[string]string{"jdoe": "jsmith"}, wanted.Renames(all, all))
+}
+
+func TestDiffRename(t *testing.T) {
+	r := require.New(t)
+
+	all := role.Map{
+		"jdoe":  role.Role{Name: "jdoe", Comment: "Managed by ldap2pg", Key: "k1"},
+		"alice": role.Role{Name: "alice", Comment: "Managed by ldap2pg", Parents: []role.Membership{{Name: "jdoe"}}},
+	}
+	wanted := role.Map{
+		"jsmith": role.Role{Name: "jsmith", Comment: "Managed by ldap2pg", Key: "k1"},
+		"alice":  role.Role{Name: "alice", Comment: "Managed by ldap2pg", Parents: []role.Membership{{Name: "jsmith"}}},
+	}
+	var descriptions []string
+	for q := range role.Diff(all, all, wanted, "postgres") {
+		descriptions = append(descriptions, q.Description)
+	}
+	r.Equal([]string{"Rename role."}, descriptions)
+	// Inspected roles are untouched.
+	r.Equal("jdoe", all["alice"].Parents[0].Name)
+}
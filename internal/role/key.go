@@ -0,0 +1,86 @@
+package role
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+)
+
+// Tag of stable key at the end of role comment.
+var keyTagRe = regexp.MustCompile(`\s*\[ldap2pg-key:([^\]]+)\]$`)
+
+// parseKey splits stable key tag from comment.
+func parseKey(comment string) (string, string) {
+	match := keyTagRe.FindStringSubmatchIndex(comment)
+	if match == nil {
+		return comment, ""
+	}
+	return comment[:match[0]], comment[match[2]:match[3]]
+}
+
+// fullComment returns comment with stable key tag, as stored in Postgres.
+func (r Role) fullComment() string {
+	if r.Key == "" {
+		return r.Comment
+	}
+	tag := fmt.Sprintf("[ldap2pg-key:%s]", r.Key)
+	if r.Comment == "" {
+		return tag
+	}
+	return r.Comment + " " + tag
+}
+
+// Renames matches wanted roles missing in Postgres with spurious managed roles
+// by stable key. Returns new name indexed by old name. A key shared by several
+// roles on either side is ambiguous and renames nothing.
+func (m Map) Renames(all, managed Map) map[string]string {
+	olds := make(map[string][]string)
+	for name, current := range managed {
+		if current.Key == "" {
+			continue
+		}
+		if _, ok := m[name]; ok {
+			continue
+		}
+		olds[current.Key] = append(olds[current.Key], name)
+	}
+
+	news := make(map[string][]string)
+	for name, wanted := range m {
+		if wanted.Key == "" {
+			continue
+		}
+		if _, ok := all[name]; ok {
+			continue
+		}
+		news[wanted.Key] = append(news[wanted.Key], name)
+	}
+
+	renames := make(map[string]string)
+	for key, names := range news {
+		candidates := olds[key]
+		if len(candidates) == 0 {
+			continue
+		}
+		if len(names) > 1 || len(candidates) > 1 {
+			slices.Sort(names)
+			slices.Sort(candidates)
+			slog.Warn("Ambiguous role key. Not renaming.", "key", key, "roles", names, "candidates", candidates)
+			continue
+		}
+		renames[candidates[0]] = names[0]
+	}
+	return renames
+}
+
+// renameParents updates current memberships to renamed parents.
+func (r *Role) renameParents(renames map[string]string) {
+	// Don't alter inspected roles.
+	r.Parents = slices.Clone(r.Parents)
+	for i, m := range r.Parents {
+		if name, ok := renames[m.Name]; ok {
+			r.Parents[i].Name = name
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package role
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseKey is internal because only RowTo parses keys, from a pgx row.
+func TestParseKey(t *testing.T) {
+	r := require.New(t)
+
+	comment, key := parseKey("Managed by ldap2pg [ldap2pg-key:102b9a4f]")
+	r.Equal("Managed by ldap2pg", comment)
+	r.Equal("102b9a4f", key)
+
+	comment, key = parseKey("Managed by ldap2pg")
+	r.Equal("Managed by ldap2pg", comment)
+	r.Equal("", key)
+
+	role := Role{Comment: "Managed by ldap2pg", Key: "102b9a4f"}
+	r.Equal("Managed by ldap2pg [ldap2pg-key:102b9a4f]", role.fullComment())
+	role.Comment = ""
+	r.Equal("[ldap2pg-key:102b9a4f]", role.fullComment())
+	comment, key = parseKey(role.fullComment())
+	r.Equal("", comment)
+	r.Equal("102b9a4f", key)
+}
@@ -1,6 +1,7 @@
 package role
 
 import (
+	"fmt"
 	"log/slog"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -39,9 +40,51 @@ func (m Map) flattenRole(r Role, seen *mapset.Set[string]) []string {
 			continue
 		}
 		names = append(names, m.flattenRole(parent, seen)...)
-
-		(*seen).Add(r.Name)
 	}
+	// Mark roles without managed parents too, to yield them once.
+	(*seen).Add(r.Name)
 	names = append(names, r.Name)
 	return names
 }
+
+// HashPasswords hashes passwords of roles to create, and of existing roles
+// with password policy always. A role renamed from a managed role exists.
+// Other passwords are not read at all.
+func (m Map) HashPasswords(all, managed Map) error {
+	renamed := make(map[string]bool)
+	for _, name := range m.Renames(all, managed) {
+		renamed[name] = true
+	}
+	for name, role := range m {
+		if role.Password.IsZero() {
+			continue
+		}
+		_, exists := all[name]
+		exists = exists || renamed[name]
+		if exists && role.Password.Policy != PasswordAlways {
+			continue
+		}
+		err := role.Password.Hash()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		m[name] = role
+	}
+	return nil
+}
+
+// CheckMembershipOptions rejects INHERIT and SET membership options before
+// Postgres 16.
+func (m Map) CheckMembershipOptions(serverVersionNum int) error {
+	if serverVersionNum >= 160000 {
+		return nil
+	}
+	for _, role := range m {
+		for _, membership := range role.Parents {
+			if membership.IsPG16() {
+				return fmt.Errorf("%s: parent %s: inherit and set options require Postgres 16", role.Name, membership.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -1,14 +1,80 @@
 package role
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Membership struct {
 	Grantor string
 	Name    string
+	// Membership options. nil means unmanaged or, before Postgres 16,
+	// unavailable.
+	Admin   *bool
+	Inherit *bool
+	Set     *bool
 }
 
 func (m Membership) String() string {
 	return m.Name
 }
 
+// HasOptions reports whether membership requires a GRANT with options.
+func (m Membership) HasOptions() bool {
+	return (m.Admin != nil && *m.Admin) || m.Inherit != nil || m.Set != nil
+}
+
+// IsPG16 reports whether membership requires Postgres 16 options.
+func (m Membership) IsPG16() bool {
+	return m.Inherit != nil || m.Set != nil
+}
+
+// grantOptions renders WITH clause of GRANT for new membership. Admin false
+// is the default.
+func (m Membership) grantOptions() string {
+	var options []string
+	if m.Admin != nil && *m.Admin {
+		// Postgres 15 and lower only accepts ADMIN OPTION.
+		options = append(options, "ADMIN OPTION")
+	}
+	if m.Inherit != nil {
+		options = append(options, fmt.Sprintf("INHERIT %s", sqlBool(*m.Inherit)))
+	}
+	if m.Set != nil {
+		options = append(options, fmt.Sprintf("SET %s", sqlBool(*m.Set)))
+	}
+	return strings.Join(options, ", ")
+}
+
+// alterOptions returns the options to grant to current membership to match
+// wanted, and whether to revoke admin option. grant keeps the grantor of
+// current membership.
+func (m Membership) alterOptions(wanted Membership) (grant Membership, revokeAdmin bool) {
+	grant.Name = m.Name
+	grant.Grantor = m.Grantor
+	if wanted.Admin != nil && m.Admin != nil && *wanted.Admin != *m.Admin {
+		if *wanted.Admin {
+			grant.Admin = wanted.Admin
+		} else {
+			revokeAdmin = true
+		}
+	}
+	if wanted.Inherit != nil && m.Inherit != nil && *wanted.Inherit != *m.Inherit {
+		grant.Inherit = wanted.Inherit
+	}
+	if wanted.Set != nil && m.Set != nil && *wanted.Set != *m.Set {
+		grant.Set = wanted.Set
+	}
+	return
+}
+
+func sqlBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
 func (r Role) MemberOf(p string) bool {
 	for _, m := range r.Parents {
 		if p == m.Name {
@@ -18,6 +84,16 @@ func (r Role) MemberOf(p string) bool {
 	return false
 }
 
+// parent returns membership of r in p.
+func (r Role) parent(p string) (Membership, bool) {
+	for _, m := range r.Parents {
+		if p == m.Name {
+			return m, true
+		}
+	}
+	return Membership{}, false
+}
+
 func (r Role) MissingParents(o []Membership) (out []Membership) {
 	for _, m := range o {
 		if !r.MemberOf(m.Name) {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/dalibo/ldap2pg/v6/internal/role"
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/require"
 )
 
@@ -42,3 +43,52 @@ func TestLoop(t *testing.T) {
 	err := m.Check()
 	r.Error(err)
 }
+
+func TestMembershipOptions(t *testing.T) {
+	r := require.New(t)
+
+	yes, no := true, false
+	current := role.Role{
+		Name: "toto",
+		Parents: []role.Membership{
+			{Name: "owners", Grantor: "postgres", Admin: &yes, Inherit: &yes, Set: &yes},
+		},
+	}
+	wanted := role.Role{
+		Name: "toto",
+		Parents: []role.Membership{
+			{Name: "owners"},
+		},
+	}
+	r.Empty(current.Alter(wanted))
+
+	wanted.Parents[0].Admin = &no
+	wanted.Parents[0].Inherit = &no
+	queries := current.Alter(wanted)
+	r.Len(queries, 2)
+	// Alter existing membership instead of adding one by another grantor.
+	r.Equal(`GRANT %s TO %s WITH INHERIT FALSE GRANTED BY %s;`, queries[0].Query)
+	r.Equal(pgx.Identifier{"postgres"}, queries[0].QueryArgs[2])
+	r.Equal(`REVOKE ADMIN OPTION FOR %s FROM %s GRANTED BY %s;`, queries[1].Query)
+
+	wanted.Parents = append(wanted.Parents, role.Membership{Name: "readers", Set: &no}, role.Membership{Name: "writers"})
+	queries = current.Alter(wanted)
+	r.Len(queries, 4)
+	r.Equal(`GRANT %s TO %s WITH SET FALSE;`, queries[0].Query)
+	r.Equal(`GRANT %s TO %s;`, queries[1].Query)
+
+	queries = wanted.Create()
+	r.Contains(queries[0].Query, "IN ROLE %s")
+	r.Equal(`GRANT %s TO %s WITH INHERIT FALSE;`, queries[1].Query)
+	r.Equal(`GRANT %s TO %s WITH SET FALSE;`, queries[2].Query)
+
+	m := role.Map{"toto": wanted}
+	r.Nil(m.CheckMembershipOptions(160000))
+	r.ErrorContains(m.CheckMembershipOptions(150004), "require Postgres 16")
+
+	wanted.Parents = []role.Membership{{Name: "owners", Admin: &yes}}
+	m = role.Map{"toto": wanted}
+	r.Nil(m.CheckMembershipOptions(150004))
+	queries = wanted.Create()
+	r.Equal(`GRANT %s TO %s WITH ADMIN OPTION;`, queries[1].Query)
+}
@@ -0,0 +1,125 @@
+package role
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/dalibo/ldap2pg/v6/internal/secret"
+)
+
+// Password policies.
+const (
+	PasswordCreateOnly = "create_only"
+	PasswordAlways     = "always"
+)
+
+const (
+	scramPrefix     = "SCRAM-SHA-256$"
+	scramIterations = 4096
+	scramSaltLength = 16
+)
+
+// Password holds the source of a role password, then its SCRAM-SHA-256
+// verifier once hashed. Clear text never leaves ldap2pg.
+type Password struct {
+	// create_only or always.
+	Policy string
+	// Source of clear text password. Only one is set.
+	Value   string
+	File    string
+	Command string
+	// SCRAM-SHA-256 verifier, computed by Hash.
+	Verifier string
+}
+
+func (p Password) IsZero() bool {
+	return p.Value == "" && p.File == "" && p.Command == ""
+}
+
+// LogValue never shows password.
+func (p Password) LogValue() slog.Value {
+	if p.IsZero() {
+		return slog.StringValue("")
+	}
+	return slog.StringValue("********")
+}
+
+func (p Password) String() string {
+	return p.LogValue().String()
+}
+
+// Hash reads clear text password from source and computes its verifier.
+func (p *Password) Hash() error {
+	var clear string
+	switch {
+	case p.Command != "":
+		var err error
+		clear, err = secret.Run(p.Command)
+		if err != nil {
+			return fmt.Errorf("password command: %w", err)
+		}
+	case p.File != "":
+		b, err := os.ReadFile(p.File)
+		if err != nil {
+			return fmt.Errorf("password file: %w", err)
+		}
+		clear = strings.TrimRight(string(b), "\r\n")
+		if clear == "" {
+			return fmt.Errorf("password file: %s: empty", p.File)
+		}
+	default:
+		clear = p.Value
+	}
+
+	if strings.HasPrefix(clear, scramPrefix) {
+		// Already hashed, e.g. from a vault.
+		p.Verifier = clear
+		return nil
+	}
+
+	salt := make([]byte, scramSaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return err
+	}
+	p.Verifier, err = ScramSHA256(clear, salt, scramIterations)
+	return err
+}
+
+// ScramSHA256 computes a SCRAM-SHA-256 verifier like Postgres stores in
+// pg_authid.rolpassword.
+//
+// Unlike Postgres, password is not normalized with SASLprep. This is the same
+// for ASCII passwords.
+//
+// cf. https://www.postgresql.org/docs/current/catalog-pg-authid.html
+func ScramSHA256(password string, salt []byte, iterations int) (string, error) {
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSHA256(salted, "Server Key")
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%s%d:%s$%s:%s",
+		scramPrefix, iterations, b64(salt), b64(storedKey[:]), b64(serverKey),
+	), nil
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(message))
+	return h.Sum(nil)
+}
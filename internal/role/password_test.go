@@ -0,0 +1,103 @@
+package role_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dalibo/ldap2pg/v6/internal/postgres"
+	"github.com/dalibo/ldap2pg/v6/internal/role"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScramSHA256(t *testing.T) {
+	r := require.New(t)
+
+	// Computed with Python hashlib.
+	v, err := role.ScramSHA256("secret", []byte("0123456789abcdef"), 4096)
+	r.Nil(err)
+	r.Equal("SCRAM-SHA-256$4096:MDEyMzQ1Njc4OWFiY2RlZg==$bpSY5Ze9NUH+I35LC3gVq+DpBfK46iXBxvhAKqVu9pE=:VpYlBuxyzeCI1KnctrefdljpB1mk3Gp7sBI/t11+NkQ=", v)
+
+	_, err = role.ScramSHA256("", []byte("0123456789abcdef"), 4096)
+	r.Error(err)
+}
+
+func TestPasswordHash(t *testing.T) {
+	r := require.New(t)
+
+	p := role.Password{Value: "secret"}
+	r.Nil(p.Hash())
+	r.Regexp(`^SCRAM-SHA-256\$4096:[^$]+\$[^:]+:.+$`, p.Verifier)
+	r.NotContains(p.Verifier, "secret")
+
+	verifier := "SCRAM-SHA-256$4096:MDEyMzQ1Njc4OWFiY2RlZg==$bpSY5Ze9NUH+I35LC3gVq+DpBfK46iXBxvhAKqVu9pE=:VpYlBuxyzeCI1KnctrefdljpB1mk3Gp7sBI/t11+NkQ="
+	p = role.Password{Value: verifier}
+	r.Nil(p.Hash())
+	r.Equal(verifier, p.Verifier)
+
+	path := filepath.Join(t.TempDir(), "password")
+	r.Nil(os.WriteFile(path, []byte(verifier+"\n"), 0o600))
+	p = role.Password{File: path}
+	r.Nil(p.Hash())
+	r.Equal(verifier, p.Verifier)
+
+	p = role.Password{Command: "echo " + verifier}
+	r.Nil(p.Hash())
+	r.Equal(verifier, p.Verifier)
+
+	p = role.Password{File: filepath.Join(t.TempDir(), "missing")}
+	r.ErrorContains(p.Hash(), "password file")
+
+	r.Equal("********", role.Password{Value: "secret"}.String())
+}
+
+func TestHashPasswords(t *testing.T) {
+	r := require.New(t)
+
+	all := role.Map{
+		"alice": role.Role{Name: "alice"},
+		"bob":   role.Role{Name: "bob"},
+		"jdoe":  role.Role{Name: "jdoe", Key: "k1"},
+	}
+	missing := role.Password{File: filepath.Join(t.TempDir(), "missing"), Policy: role.PasswordCreateOnly}
+	wanted := role.Map{
+		"alice": role.Role{Name: "alice", Password: role.Password{Value: "a", Policy: role.PasswordCreateOnly}},
+		"bob":   role.Role{Name: "bob", Password: role.Password{Value: "b", Policy: role.PasswordAlways}},
+		"carol": role.Role{Name: "carol", Password: role.Password{Value: "c", Policy: role.PasswordCreateOnly}},
+		// Renamed from jdoe, password source is not read.
+		"jsmith": role.Role{Name: "jsmith", Key: "k1", Password: missing},
+	}
+	r.Nil(wanted.HashPasswords(all, all))
+	r.Empty(wanted["alice"].Password.Verifier)
+	r.NotEmpty(wanted["bob"].Password.Verifier)
+	r.NotEmpty(wanted["carol"].Password.Verifier)
+	r.Empty(wanted["jsmith"].Password.Verifier)
+}
+
+func TestPasswordQueries(t *testing.T) {
+	r := require.New(t)
+
+	password := role.Password{Value: "secret", Policy: role.PasswordCreateOnly}
+	r.Nil(password.Hash())
+	wanted := role.Role{Name: "alice", Password: password}
+
+	queries := wanted.Create()
+	var found bool
+	for _, q := range queries {
+		if q.Description != "Set role password." {
+			continue
+		}
+		found = true
+		r.Equal(postgres.Secret(password.Verifier), q.QueryArgs[1])
+		r.NotContains(q.LogArgs, password.Verifier)
+	}
+	r.True(found)
+
+	current := role.Role{Name: "alice"}
+	r.Empty(current.Alter(wanted))
+
+	wanted.Password.Policy = role.PasswordAlways
+	queries = current.Alter(wanted)
+	r.Len(queries, 1)
+	r.Equal("Set role password.", queries[0].Description)
+}
@@ -12,11 +12,16 @@ import (
 )
 
 type Role struct {
-	Name         string
-	Comment      string
-	Parents      []Membership
-	Options      Options
-	Config       Config
+	Name     string
+	Comment  string
+	Parents  []Membership
+	Options  Options
+	Config   Config
+	Password Password
+	// Canonical VALID UNTIL timestamp. Empty means unmanaged.
+	ValidUntil string
+	// Stable identifier of role, tagged in comment to track renames.
+	Key          string
 	BeforeCreate string
 	AfterCreate  string
 }
@@ -32,7 +37,7 @@ func RowTo(row pgx.CollectableRow) (r Role, err error) {
 	var parents []any // jsonb
 	var config []string
 	r = New()
-	err = row.Scan(&r.Name, &variableRow, &r.Comment, &parents, &config)
+	err = row.Scan(&r.Name, &variableRow, &r.Comment, &parents, &config, &r.ValidUntil)
 	if err != nil {
 		return
 	}
@@ -47,6 +52,7 @@ func RowTo(row pgx.CollectableRow) (r Role, err error) {
 		}
 		r.Parents = append(r.Parents, m)
 	}
+	r.Comment, r.Key = parseKey(r.Comment)
 	r.Options.LoadRow(variableRow.([]any))
 	r.Config.Parse(config)
 	return
@@ -98,7 +104,31 @@ func (r *Role) Alter(wanted Role) (out []postgres.SyncQuery) {
 		})
 	}
 
-	missingMemberships := r.MissingParents(wanted.Parents)
+	if wanted.Password.Verifier != "" && wanted.Password.Policy == PasswordAlways {
+		out = append(out, r.setPassword(wanted.Password))
+	}
+
+	if wanted.ValidUntil != "" && wanted.ValidUntil != r.ValidUntil {
+		out = append(out, postgres.SyncQuery{
+			Description: "Set role expiration.",
+			LogArgs: []any{
+				"role", r.Name,
+				"current", r.ValidUntil,
+				"wanted", wanted.ValidUntil,
+			},
+			Query:     `ALTER ROLE %s VALID UNTIL %s;`,
+			QueryArgs: []any{identifier, wanted.ValidUntil},
+		})
+	}
+
+	var missingMemberships []Membership
+	for _, membership := range r.MissingParents(wanted.Parents) {
+		if membership.HasOptions() {
+			out = append(out, r.grantParent(membership))
+		} else {
+			missingMemberships = append(missingMemberships, membership)
+		}
+	}
 	if len(missingMemberships) > 0 {
 		var parentIdentifiers []any
 		for _, membership := range missingMemberships {
@@ -114,6 +144,29 @@ func (r *Role) Alter(wanted Role) (out []postgres.SyncQuery) {
 			QueryArgs: []any{parentIdentifiers, identifier},
 		})
 	}
+
+	for _, membership := range wanted.Parents {
+		current, ok := r.parent(membership.Name)
+		if !ok {
+			continue
+		}
+		grant, revokeAdmin := current.alterOptions(membership)
+		if grant.HasOptions() {
+			out = append(out, r.grantParent(grant))
+		}
+		if revokeAdmin {
+			out = append(out, postgres.SyncQuery{
+				Description: "Revoke parent admin option.",
+				LogArgs: []any{
+					"role", r.Name,
+					"parent", current.Name,
+					"grantor", current.Grantor,
+				},
+				Query:     `REVOKE ADMIN OPTION FOR %s FROM %s GRANTED BY %s;`,
+				QueryArgs: []any{pgx.Identifier{current.Name}, identifier, pgx.Identifier{current.Grantor}},
+			})
+		}
+	}
 	spuriousMemberships := wanted.MissingParents(r.Parents)
 	for _, membership := range spuriousMemberships {
 		out = append(out, postgres.SyncQuery{
@@ -136,16 +189,16 @@ func (r *Role) Alter(wanted Role) (out []postgres.SyncQuery) {
 		})
 	}
 
-	if wanted.Comment != r.Comment {
+	if wanted.fullComment() != r.fullComment() {
 		out = append(out, postgres.SyncQuery{
 			Description: "Set role comment.",
 			LogArgs: []any{
 				"role", r.Name,
-				"current", r.Comment,
-				"wanted", wanted.Comment,
+				"current", r.fullComment(),
+				"wanted", wanted.fullComment(),
 			},
 			Query:     `COMMENT ON ROLE %s IS %s;`,
-			QueryArgs: []any{identifier, wanted.Comment},
+			QueryArgs: []any{identifier, wanted.fullComment()},
 		})
 	}
 
@@ -216,33 +269,55 @@ func (r *Role) Create() (out []postgres.SyncQuery) {
 		})
 	}
 
-	if len(r.Parents) > 0 {
+	options := r.Options.String()
+	args := []any{identifier}
+	if r.ValidUntil != "" {
+		options += ` VALID UNTIL %s`
+		args = append(args, r.ValidUntil)
+	}
+	// IN ROLE clause does not accept membership options. Grant them after
+	// creation.
+	var inRole, withOptions []Membership
+	for _, parent := range r.Parents {
+		if parent.HasOptions() {
+			withOptions = append(withOptions, parent)
+		} else {
+			inRole = append(inRole, parent)
+		}
+	}
+	if len(inRole) > 0 {
 		parents := []any{}
-		for _, parent := range r.Parents {
+		for _, parent := range inRole {
 			parents = append(parents, pgx.Identifier{parent.Name})
 		}
 		out = append(out, postgres.SyncQuery{
 			Description: "Create role.",
-			LogArgs:     []any{"role", r.Name, "parents", r.Parents},
+			LogArgs:     []any{"role", r.Name, "parents", inRole},
 			Query: `
 			CREATE ROLE %s
-			WITH ` + r.Options.String() + `
+			WITH ` + options + `
 			IN ROLE %s;`,
-			QueryArgs: []any{identifier, parents},
+			QueryArgs: append(args, parents),
 		})
 	} else {
 		out = append(out, postgres.SyncQuery{
 			Description: "Create role.",
 			LogArgs:     []any{"role", r.Name},
-			Query:       `CREATE ROLE %s WITH ` + r.Options.String() + `;`,
-			QueryArgs:   []any{identifier},
+			Query:       `CREATE ROLE %s WITH ` + options + `;`,
+			QueryArgs:   args,
 		})
 	}
+	for _, parent := range withOptions {
+		out = append(out, r.grantParent(parent))
+	}
+	if r.Password.Verifier != "" {
+		out = append(out, r.setPassword(r.Password))
+	}
 	out = append(out, postgres.SyncQuery{
 		Description: "Set role comment.",
 		LogArgs:     []any{"role", r.Name},
 		Query:       `COMMENT ON ROLE %s IS %s;`,
-		QueryArgs:   []any{identifier, r.Comment},
+		QueryArgs:   []any{identifier, r.fullComment()},
 	})
 
 	if r.Config != nil {
@@ -267,6 +342,50 @@ func (r *Role) Create() (out []postgres.SyncQuery) {
 	return
 }
 
+// grantParent grants membership with options. If m has a grantor, the grant
+// alters options of this existing membership. On Postgres 16, a grant by
+// another grantor would add a second membership.
+func (r *Role) grantParent(m Membership) postgres.SyncQuery {
+	q := postgres.SyncQuery{
+		Description: "Grant parent with options.",
+		LogArgs: []any{
+			"role", r.Name,
+			"parent", m.Name,
+			"options", m.grantOptions(),
+		},
+		Query:     `GRANT %s TO %s WITH ` + m.grantOptions() + `;`,
+		QueryArgs: []any{pgx.Identifier{m.Name}, pgx.Identifier{r.Name}},
+	}
+	if m.Grantor != "" {
+		q.LogArgs = append(q.LogArgs, "grantor", m.Grantor)
+		q.Query = `GRANT %s TO %s WITH ` + m.grantOptions() + ` GRANTED BY %s;`
+		q.QueryArgs = append(q.QueryArgs, pgx.Identifier{m.Grantor})
+	}
+	return q
+}
+
+// setPassword sends the SCRAM-SHA-256 verifier of password, never clear text.
+func (r *Role) setPassword(password Password) postgres.SyncQuery {
+	return postgres.SyncQuery{
+		Description: "Set role password.",
+		LogArgs:     []any{"role", r.Name, "policy", password.Policy},
+		Query:       `ALTER ROLE %s WITH PASSWORD %s;`,
+		QueryArgs:   []any{pgx.Identifier{r.Name}, postgres.Secret(password.Verifier)},
+	}
+}
+
+// Rename generates query to rename role, then updates r.
+func (r *Role) Rename(name string) postgres.SyncQuery {
+	q := postgres.SyncQuery{
+		Description: "Rename role.",
+		LogArgs:     []any{"role", r.Name, "name", name, "key", r.Key},
+		Query:       `ALTER ROLE %s RENAME TO %s;`,
+		QueryArgs:   []any{pgx.Identifier{r.Name}, pgx.Identifier{name}},
+	}
+	r.Name = name
+	return q
+}
+
 func (r *Role) Drop(fallbackOwner string) (out []postgres.SyncQuery) {
 	identifier := pgx.Identifier{r.Name}
 	if r.Options.CanLogin {
@@ -336,6 +455,15 @@ func (r *Role) Merge(o Role) {
 		}
 		r.Parents = append(r.Parents, membership)
 	}
+	if r.Password.IsZero() {
+		r.Password = o.Password
+	}
+	if r.ValidUntil == "" {
+		r.ValidUntil = o.ValidUntil
+	}
+	if r.Key == "" {
+		r.Key = o.Key
+	}
 	if r.Config == nil {
 		r.Config = o.Config
 	} else if o.Config != nil {
@@ -24,3 +24,16 @@ func TestMerge(t *testing.T) {
 	r0.Merge(r1)
 	r.Equal("tata", r0.Config["a"])
 }
+
+func TestFlatten(t *testing.T) {
+	r := require.New(t)
+
+	m := role.Map{
+		"parent": role.Role{Name: "parent"},
+		"child":  role.Role{Name: "child", Parents: []role.Membership{{Name: "parent"}}},
+		"other":  role.Role{Name: "other", Parents: []role.Membership{{Name: "parent"}}},
+	}
+	names := m.Flatten()
+	r.Len(names, 3)
+	r.Equal("parent", names[0])
+}
@@ -0,0 +1,37 @@
+package role
+
+import (
+	"strings"
+	"time"
+)
+
+// Canonical form of VALID UNTIL, like roles inspect query renders
+// rolvaliduntil.
+const validUntilLayout = "2006-01-02T15:04:05Z"
+
+// Timestamps accepted for VALID UNTIL. Timestamps without time zone are UTC.
+var validUntilLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// NormalizeValidUntil returns canonical form of VALID UNTIL timestamp to
+// compare with inspected rolvaliduntil. Returns s as is if it's not a known
+// timestamp. Postgres will then validate it.
+func NormalizeValidUntil(s string) string {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "infinity", "-infinity":
+		return strings.ToLower(s)
+	}
+	for _, layout := range validUntilLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t.UTC().Format(validUntilLayout)
+		}
+	}
+	return s
+}
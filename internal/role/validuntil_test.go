@@ -0,0 +1,47 @@
+package role_test
+
+import (
+	"testing"
+
+	"github.com/dalibo/ldap2pg/v6/internal/role"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeValidUntil(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal("", role.NormalizeValidUntil(""))
+	r.Equal("infinity", role.NormalizeValidUntil("Infinity"))
+	r.Equal("2025-12-31T00:00:00Z", role.NormalizeValidUntil("2025-12-31"))
+	r.Equal("2025-12-31T10:30:00Z", role.NormalizeValidUntil("2025-12-31 10:30:00"))
+	r.Equal("2025-12-31T08:30:00Z", role.NormalizeValidUntil("2025-12-31T10:30:00+02:00"))
+	// Let Postgres reject it.
+	r.Equal("tomorrow", role.NormalizeValidUntil("tomorrow"))
+}
+
+func TestValidUntilQueries(t *testing.T) {
+	r := require.New(t)
+
+	wanted := role.Role{Name: "alice", ValidUntil: "2025-12-31T00:00:00Z"}
+	queries := wanted.Create()
+	r.Contains(queries[0].Query, "VALID UNTIL %s")
+	r.Equal([]any{"2025-12-31T00:00:00Z"}, queries[0].QueryArgs[1:])
+
+	wanted.Parents = []role.Membership{{Name: "staff"}}
+	queries = wanted.Create()
+	r.Contains(queries[0].Query, "VALID UNTIL %s")
+	r.Len(queries[0].QueryArgs, 3)
+	r.Equal("2025-12-31T00:00:00Z", queries[0].QueryArgs[1])
+
+	current := role.Role{Name: "alice", Parents: wanted.Parents, ValidUntil: wanted.ValidUntil}
+	r.Empty(current.Alter(wanted))
+
+	current.ValidUntil = "infinity"
+	queries = current.Alter(wanted)
+	r.Len(queries, 1)
+	r.Equal("Set role expiration.", queries[0].Description)
+
+	// Unmanaged expiration.
+	wanted.ValidUntil = ""
+	r.Empty(current.Alter(wanted))
+}
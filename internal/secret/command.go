@@ -0,0 +1,102 @@
+// Package secret retrieves passwords from external commands, like a vault CLI.
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Timeout of a secret command.
+var Timeout = 30 * time.Second
+
+// Run executes command and returns its standard output, trimmed.
+//
+// command is split like a shell does, honoring quotes and backslashes, but
+// runs without shell: no expansion, no pipe, no redirection. Output is never
+// logged.
+func Run(command string) (string, error) {
+	args, err := Split(command)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	slog.Debug("Running secret command.", "cmd", args[0])
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	// Don't hang on a child process holding output.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("%s: timed out after %s", args[0], Timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", fmt.Errorf("%s: empty output", args[0])
+	}
+	return secret, nil
+}
+
+// Split command line in arguments, honoring single quotes, double quotes and
+// backslashes like POSIX shell.
+func Split(command string) (args []string, err error) {
+	var b strings.Builder
+	// Whether an argument is pending, even empty like ''.
+	pending := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune(`"\$`+"`", r) {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			pending = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			b.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			pending = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if pending {
+				args = append(args, b.String())
+				b.Reset()
+				pending = false
+			}
+		default:
+			b.WriteRune(r)
+			pending = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if pending {
+		args = append(args, b.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	r := require.New(t)
+
+	args, err := Split(`vault kv get -field=password secret/ldap`)
+	r.NoError(err)
+	r.Equal([]string{"vault", "kv", "get", "-field=password", "secret/ldap"}, args)
+
+	args, err = Split(`  pass  show 'ldap/bind dn' "a \"b\" \c" d\ e '' `)
+	r.NoError(err)
+	r.Equal([]string{"pass", "show", "ldap/bind dn", `a "b" \c`, "d e", ""}, args)
+
+	args, err = Split(``)
+	r.NoError(err)
+	r.Empty(args)
+
+	_, err = Split(`echo 'unterminated`)
+	r.ErrorContains(err, "unterminated ' quote")
+
+	_, err = Split(`echo \`)
+	r.ErrorContains(err, "trailing backslash")
+}
+
+func TestRun(t *testing.T) {
+	r := require.New(t)
+
+	s, err := Run(`printf '%s\n' 's3cr3t $HOME'`)
+	r.NoError(err)
+	r.Equal("s3cr3t $HOME", s)
+
+	_, err = Run(`true`)
+	r.ErrorContains(err, "empty output")
+
+	_, err = Run(`sh -c 'echo denied >&2; exit 3'`)
+	r.ErrorContains(err, "exit status 3: denied")
+
+	_, err = Run(`/nonexistent/vault`)
+	r.Error(err)
+
+	defer func(d time.Duration) { Timeout = d }(Timeout)
+	Timeout = 10 * time.Millisecond
+	_, err = Run(`sleep 5`)
+	r.ErrorContains(err, "timed out")
+}
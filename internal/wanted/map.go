@@ -1,6 +1,7 @@
 package wanted
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -23,6 +24,68 @@ func (m Rules) HasLDAPSearches() bool {
 	return false
 }
 
+// connectDirectory opens a pool once per named directory.
+func connectDirectory(pools map[string]*ldap.Pool, directory string, concurrency int) (*ldap.Pool, error) {
+	p, ok := pools[directory]
+	if ok {
+		return p, nil
+	}
+	p, err := ldap.ConnectPool(directory, concurrency)
+	if err != nil {
+		return nil, err
+	}
+	pools[directory] = p
+	return p, nil
+}
+
+// openLDIF loads LDIF file once for all rules.
+func openLDIF(ldifs map[string]*ldap.Pool, path string, concurrency int) (*ldap.Pool, error) {
+	p, ok := ldifs[path]
+	if ok {
+		return p, nil
+	}
+	c, err := ldap.OpenLDIF(path)
+	if err != nil {
+		return nil, err
+	}
+	// LDIF client is read-only, share it.
+	clients := make([]ldap.Client, concurrency)
+	for i := range clients {
+		clients[i] = c
+	}
+	p = ldap.NewPool(clients...)
+	ldifs[path] = p
+	return p, nil
+}
+
+// searchAll searches steps concurrently. Returns a channel per step to
+// process results in step order.
+func (m Rules) searchAll(pools []*ldap.Pool, concurrency int) []chan []SearchResult {
+	slots := make([]chan []SearchResult, len(m))
+	for i := range slots {
+		slots[i] = make(chan []SearchResult, 1)
+	}
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range m {
+			jobs <- i
+		}
+	}()
+	for range min(concurrency, len(m)) {
+		go func() {
+			for i := range jobs {
+				var results []SearchResult
+				for res := range m[i].search(pools[i]) {
+					results = append(results, res)
+				}
+				slots[i] <- results
+			}
+		}()
+	}
+	return slots
+}
+
 func (m Rules) SplitStaticRules() (newMap Rules) {
 	newMap = make(Rules, 0)
 	for _, item := range m {
@@ -44,19 +107,41 @@ func (m Rules) DropGrants() (out Rules) {
 	return
 }
 
-func (m Rules) Run(blacklist lists.Blacklist) (roles role.Map, grants map[string][]privileges.Grant, err error) {
+// Run searches directory and generates wanted roles and grants.
+//
+// concurrency bounds the number of concurrent LDAP searches, and thus the
+// number of LDAP connections. Results are merged in rules order, whatever
+// concurrency.
+func (m Rules) Run(blacklist lists.Blacklist, concurrency int) (roles role.Map, grants map[string][]privileges.Grant, err error) {
+	if concurrency < 1 {
+		return nil, nil, fmt.Errorf("bad LDAP concurrency: %d", concurrency)
+	}
 	var errList []error
-	var ldapc ldap.Client
-	if m.HasLDAPSearches() {
-		ldapc, err = ldap.Connect()
+	pools := make([]*ldap.Pool, len(m))
+	directories := make(map[string]*ldap.Pool)
+	defer func() {
+		for _, p := range directories {
+			_ = p.Close()
+		}
+	}()
+	ldifs := make(map[string]*ldap.Pool)
+	for i, item := range m {
+		if !item.HasLDAPSearch() {
+			continue
+		}
+		if item.LdapSearch.LDIF != "" {
+			pools[i], err = openLDIF(ldifs, item.LdapSearch.LDIF, concurrency)
+		} else {
+			pools[i], err = connectDirectory(directories, item.LdapSearch.Directory, concurrency)
+		}
 		if err != nil {
 			return nil, nil, err
 		}
-		defer ldapc.Conn.Close() //nolint:errcheck
 	}
 
 	roles = make(map[string]role.Role)
 	grants = make(map[string][]privileges.Grant)
+	slots := m.searchAll(pools, concurrency)
 	for i, item := range m {
 		if item.Description != "" {
 			slog.Info(item.Description)
@@ -64,14 +149,26 @@ func (m Rules) Run(blacklist lists.Blacklist) (roles role.Map, grants map[string
 			slog.Debug("Processing sync map item.", "item", i)
 		}
 
-		for res := range item.search(ldapc) {
+		nestings := make(nestings)
+		for _, res := range <-slots[i] {
 			if res.err != nil {
-				slog.Error("Search error. Keep going.", "err", res.err)
+				var transient ldap.TransientError
+				if errors.As(res.err, &transient) {
+					slog.Error("Transient search error. Retry later. Keep going.", "err", res.err)
+				} else {
+					slog.Error("Search error. Keep going.", "err", res.err)
+				}
 				errList = append(errList, res.err)
 				continue
 			}
 
-			for role := range item.generateRoles(&res.result) {
+			for generated := range item.generateRoles(&res.result, nestings) {
+				if generated.err != nil {
+					slog.Error("Generation error. Keep going.", "err", generated.err)
+					errList = append(errList, generated.err)
+					continue
+				}
+				role := generated.role
 				if role.Name == "" {
 					continue
 				}
@@ -97,7 +194,13 @@ func (m Rules) Run(blacklist lists.Blacklist) (roles role.Map, grants map[string
 				roles[role.Name] = role
 			}
 
-			for grant := range item.generateGrants(&res.result) {
+			for generated := range item.generateGrants(&res.result) {
+				if generated.Err != nil {
+					slog.Error("Generation error. Keep going.", "err", generated.Err)
+					errList = append(errList, generated.Err)
+					continue
+				}
+				grant := generated.Grant
 				pattern := blacklist.MatchString(grant.Grantee)
 				if pattern != "" {
 					slog.Debug(
@@ -114,6 +217,7 @@ func (m Rules) Run(blacklist lists.Blacklist) (roles role.Map, grants map[string
 				grants[grant.ACL] = append(grants[grant.ACL], grant)
 			}
 		}
+		nestings.mirror(roles)
 	}
 
 	err = roles.Check()
@@ -126,3 +230,41 @@ func (m Rules) Run(blacklist lists.Blacklist) (roles role.Map, grants map[string
 	}
 	return
 }
+
+// Listen notifies changes of entries matched by searches of rules until ctx is
+// done. Searches of LDIF files are not listened. Returns a channel of fatal
+// listening errors.
+func (m Rules) Listen(ctx context.Context, changes chan<- string) <-chan error {
+	type key struct {
+		directory, base, filter string
+		scope                   ldap.Scope
+	}
+	seen := make(map[key]bool)
+	var searches []ldap.Search
+	for _, item := range m {
+		if !item.HasLDAPSearch() || item.LdapSearch.LDIF != "" {
+			continue
+		}
+		s := item.LdapSearch
+		k := key{s.Directory, s.Base, s.Filter, s.Scope}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		searches = append(searches, s)
+	}
+	if len(searches) == 0 {
+		slog.Warn("No LDAP search to listen. Only periodic synchronization.")
+	}
+
+	errs := make(chan error, len(searches))
+	for _, s := range searches {
+		go func() {
+			err := ldap.Listen(ctx, s, changes)
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+	return errs
+}
@@ -0,0 +1,103 @@
+package wanted
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/dalibo/ldap2pg/v6/internal/ldap"
+	"github.com/dalibo/ldap2pg/v6/internal/role"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// nesting mirrors LDAP group nesting as role membership.
+//
+// Tracks roles generated from each entry and members of each entry. Once all
+// entries of a search are processed, grants roles of a group to roles of its
+// members.
+type nesting struct {
+	// Normalized DN -> role names generated from entry.
+	roles map[string][]string
+	// Normalized DN -> normalized DN of members.
+	members map[string][]string
+	// Keep DN order for stable membership order.
+	order []string
+}
+
+// nestingKey identifies the nesting of a role rule of a step.
+type nestingKey struct {
+	// Index of role rule in step.
+	rule      int
+	attribute string
+}
+
+// nestings indexes nesting by role rule and member attribute. Each rule mirrors
+// nesting between its own roles.
+type nestings map[nestingKey]*nesting
+
+func (ns nestings) track(result *ldap.Result, rule int, attribute, name string) {
+	key := nestingKey{rule: rule, attribute: attribute}
+	n, ok := ns[key]
+	if !ok {
+		n = &nesting{
+			roles:   make(map[string][]string),
+			members: make(map[string][]string),
+		}
+		ns[key] = n
+	}
+	n.track(result, attribute, name)
+}
+
+func (ns nestings) mirror(roles role.Map) {
+	keys := maps.Keys(ns)
+	slices.SortFunc(keys, func(a, b nestingKey) int {
+		if a.rule != b.rule {
+			return a.rule - b.rule
+		}
+		return strings.Compare(a.attribute, b.attribute)
+	})
+	for _, key := range keys {
+		ns[key].mirror(roles)
+	}
+}
+
+func (n *nesting) track(result *ldap.Result, attribute, name string) {
+	dn := ldap.NormalizeDN(result.Entry.DN)
+	if _, ok := n.members[dn]; !ok {
+		n.order = append(n.order, dn)
+		members := []string{}
+		for _, member := range result.Entry.GetEqualFoldAttributeValues(attribute) {
+			members = append(members, ldap.NormalizeDN(member))
+		}
+		n.members[dn] = members
+	}
+	n.roles[dn] = append(n.roles[dn], name)
+}
+
+// mirror grants roles of groups to roles of member groups.
+func (n *nesting) mirror(roles role.Map) {
+	for _, dn := range n.order {
+		for _, member := range n.members[dn] {
+			children, ok := n.roles[member]
+			if !ok {
+				// Member is not a group of the search.
+				continue
+			}
+			for _, childName := range children {
+				child, ok := roles[childName]
+				if !ok {
+					// Blacklisted role.
+					continue
+				}
+				for _, parentName := range n.roles[dn] {
+					if _, ok := roles[parentName]; !ok || child.MemberOf(parentName) {
+						continue
+					}
+					slog.Debug("Mirroring group nesting.", "role", childName, "parent", parentName)
+					child.Parents = append(child.Parents, role.Membership{Name: parentName})
+				}
+				roles[childName] = child
+			}
+		}
+	}
+}
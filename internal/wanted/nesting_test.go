@@ -0,0 +1,72 @@
+package wanted
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dalibo/ldap2pg/v6/internal/ldap"
+	"github.com/dalibo/ldap2pg/v6/internal/role"
+	ldap3 "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorNesting(t *testing.T) {
+	r := require.New(t)
+
+	entries := []*ldap3.Entry{
+		ldap3.NewEntry("cn=readers,ou=groups,dc=acme", map[string][]string{
+			"member": {"CN=DBA,ou=groups,dc=acme", "cn=alice,ou=people,dc=acme"},
+		}),
+		ldap3.NewEntry("cn=dba,ou=groups,dc=acme", map[string][]string{
+			"member": {"cn=bob,ou=people,dc=acme"},
+		}),
+		ldap3.NewEntry("cn=blacklisted,ou=groups,dc=acme", map[string][]string{
+			"member": {"cn=dba,ou=groups,dc=acme"},
+		}),
+	}
+	roles := role.Map{
+		"readers": role.Role{Name: "readers"},
+		"dba":     role.Role{Name: "dba"},
+	}
+	ns := make(nestings)
+	for _, entry := range entries {
+		name, _, _ := strings.Cut(strings.TrimPrefix(entry.DN, "cn="), ",")
+		ns.track(&ldap.Result{Entry: entry}, 0, "member", name)
+	}
+	ns.mirror(roles)
+
+	r.Equal([]role.Membership{{Name: "readers"}}, roles["dba"].Parents)
+	r.Empty(roles["readers"].Parents)
+
+	// Mirroring twice does not duplicate membership.
+	ns.mirror(roles)
+	r.Len(roles["dba"].Parents, 1)
+}
+
+func TestMirrorNestingPerRule(t *testing.T) {
+	r := require.New(t)
+
+	entries := []*ldap3.Entry{
+		ldap3.NewEntry("cn=readers,ou=groups,dc=acme", map[string][]string{
+			"member": {"cn=dba,ou=groups,dc=acme"},
+		}),
+		ldap3.NewEntry("cn=dba,ou=groups,dc=acme", nil),
+	}
+	roles := role.Map{
+		"readers":    role.Role{Name: "readers"},
+		"dba":        role.Role{Name: "dba"},
+		"readers_ro": role.Role{Name: "readers_ro"},
+		"dba_ro":     role.Role{Name: "dba_ro"},
+	}
+	// Two rules of a step mirror nesting of the same attribute.
+	ns := make(nestings)
+	for _, entry := range entries {
+		name, _, _ := strings.Cut(strings.TrimPrefix(entry.DN, "cn="), ",")
+		ns.track(&ldap.Result{Entry: entry}, 0, "member", name)
+		ns.track(&ldap.Result{Entry: entry}, 1, "member", name+"_ro")
+	}
+	ns.mirror(roles)
+
+	r.Equal([]role.Membership{{Name: "readers"}}, roles["dba"].Parents)
+	r.Equal([]role.Membership{{Name: "readers_ro"}}, roles["dba_ro"].Parents)
+}
@@ -1,6 +1,10 @@
 package wanted
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/dalibo/ldap2pg/v6/internal/ldap"
 	"github.com/dalibo/ldap2pg/v6/internal/lists"
 	"github.com/dalibo/ldap2pg/v6/internal/pyfmt"
@@ -8,13 +12,23 @@ import (
 )
 
 type RoleRule struct {
-	Name         pyfmt.Format
-	Options      role.Options
-	Comment      pyfmt.Format
-	Parents      []MembershipRule
-	Config       role.Config
+	Name     pyfmt.Format
+	Options  role.Options
+	Comment  pyfmt.Format
+	Parents  []MembershipRule
+	Config   role.Config
+	Password PasswordRule
+	// create_only or always.
+	PasswordPolicy string       `mapstructure:"password_policy"`
+	ValidUntil     pyfmt.Format `mapstructure:"valid_until"`
+	// Disabled roles lose LOGIN option.
+	Disabled pyfmt.Format
+	// Stable identifier of entry to track renames, e.g. objectGUID.
+	Key          pyfmt.Format
 	BeforeCreate pyfmt.Format `mapstructure:"before_create"`
 	AfterCreate  pyfmt.Format `mapstructure:"after_create"`
+	// Attribute listing members of group to mirror group nesting.
+	MirrorNesting string `mapstructure:"mirror_nesting"`
 }
 
 func (r RoleRule) IsStatic() bool {
@@ -22,54 +36,119 @@ func (r RoleRule) IsStatic() bool {
 }
 
 func (r RoleRule) Formats() []pyfmt.Format {
-	fmts := []pyfmt.Format{r.Name, r.Comment, r.BeforeCreate, r.AfterCreate}
+	fmts := []pyfmt.Format{r.Name, r.Comment, r.BeforeCreate, r.AfterCreate, r.ValidUntil, r.Disabled, r.Key}
+	fmts = append(fmts, r.Password.Formats()...)
 	for _, p := range r.Parents {
 		fmts = append(fmts, p.Name)
 	}
 	return fmts
 }
 
-func (r RoleRule) Generate(results *ldap.Result) <-chan role.Role {
-	ch := make(chan role.Role)
+// GeneratedRole holds a role generated from a result or the error of its
+// generation.
+type GeneratedRole struct {
+	role role.Role
+	err  error
+}
+
+func (r RoleRule) Generate(results *ldap.Result) <-chan GeneratedRole {
+	ch := make(chan GeneratedRole)
 	go func() {
 		defer close(ch)
+		var errs []error
 		parents := []role.Membership{}
 		for _, m := range r.Parents {
 			if results.Entry == nil || len(m.Name.Fields) == 0 {
 				// Static case.
-				parents = append(parents, m.Generate(nil))
+				parent, err := m.Generate(nil)
+				errs = append(errs, err)
+				parents = append(parents, parent)
 			} else {
 				// Dynamic case.
-				for values := range results.GenerateValues(m.Name) {
-					parents = append(parents, m.Generate(values))
+				vchan, err := results.GenerateValues(m.Name)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				for values := range vchan {
+					parent, err := m.Generate(values)
+					errs = append(errs, err)
+					parents = append(parents, parent)
 				}
 			}
 		}
 
+		err := errors.Join(errs...)
+		if err != nil {
+			ch <- GeneratedRole{err: fmt.Errorf("%s: %w", results.Entry.DN, err)}
+			return
+		}
+
 		if nil == results.Entry {
 			// Case static rule.
+			password, err := r.Password.Generate(nil)
+			if err != nil {
+				ch <- GeneratedRole{err: err}
+				return
+			}
+			password.Policy = r.PasswordPolicy
+			validUntil := role.NormalizeValidUntil(r.ValidUntil.String())
+			options := r.Options
+			if isTrue(r.Disabled.String()) {
+				options.CanLogin = false
+			}
 			role := role.Role{
 				Name:         r.Name.String(),
 				Comment:      r.Comment.String(),
-				Options:      r.Options,
+				Options:      options,
 				Parents:      parents,
 				Config:       r.Config,
+				Password:     password,
+				ValidUntil:   validUntil,
+				Key:          r.Key.String(),
 				BeforeCreate: r.BeforeCreate.String(),
 				AfterCreate:  r.AfterCreate.String(),
 			}
-			ch <- role
+			ch <- GeneratedRole{role: role}
 		} else {
 			// Case dynamic rule.
-			for values := range results.GenerateValues(r.Name, r.Comment, r.BeforeCreate, r.AfterCreate) {
+			// Role is generated even if entry has no password,
+			// expiration, disabled flag or key.
+			fmts := []pyfmt.Format{r.Name, r.Comment, r.BeforeCreate, r.AfterCreate}
+			optional := append(r.Password.Formats(), r.ValidUntil, r.Disabled, r.Key)
+			vchan, err := results.GenerateOptionalValues(fmts, optional)
+			if err != nil {
+				ch <- GeneratedRole{err: fmt.Errorf("%s: %w", results.Entry.DN, err)}
+				return
+			}
+			for values := range vchan {
+				var errs [8]error
+				var disabled, validUntil string
+				disabled, errs[0] = r.Disabled.Render(values)
+				validUntil, errs[1] = r.ValidUntil.Render(values)
+				validUntil = role.NormalizeValidUntil(validUntil)
 				role := role.Role{}
-				role.Name = r.Name.Format(values)
-				role.Comment = r.Comment.Format(values)
+				role.Name, errs[2] = r.Name.Render(values)
+				role.Comment, errs[3] = r.Comment.Render(values)
 				role.Options = r.Options
+				if isTrue(disabled) {
+					role.Options.CanLogin = false
+				}
 				role.Parents = append(parents[0:0], parents...) // copy
 				role.Config = r.Config
-				role.BeforeCreate = r.BeforeCreate.Format(values)
-				role.AfterCreate = r.AfterCreate.Format(values)
-				ch <- role
+				role.Password, errs[4] = r.Password.Generate(values)
+				role.Password.Policy = r.PasswordPolicy
+				role.ValidUntil = validUntil
+				role.Key, errs[5] = r.Key.Render(values)
+				role.BeforeCreate, errs[6] = r.BeforeCreate.Render(values)
+				role.AfterCreate, errs[7] = r.AfterCreate.Render(values)
+				err := errors.Join(errs[:]...)
+				if err != nil {
+					// Keep draining values.
+					ch <- GeneratedRole{err: fmt.Errorf("%s: %w", results.Entry.DN, err)}
+					continue
+				}
+				ch <- GeneratedRole{role: role}
 			}
 		}
 	}()
@@ -78,6 +157,10 @@ func (r RoleRule) Generate(results *ldap.Result) <-chan role.Role {
 
 type MembershipRule struct {
 	Name pyfmt.Format
+	// Membership options. nil means unmanaged.
+	Admin   *bool
+	Inherit *bool
+	Set     *bool
 }
 
 func (m MembershipRule) String() string {
@@ -88,8 +171,44 @@ func (m MembershipRule) IsStatic() bool {
 	return m.Name.IsStatic()
 }
 
-func (m MembershipRule) Generate(values map[string]string) role.Membership {
+func (m MembershipRule) Generate(values map[string]string) (role.Membership, error) {
+	name, err := m.Name.Render(values)
 	return role.Membership{
-		Name: m.Name.Format(values),
+		Name:    name,
+		Admin:   m.Admin,
+		Inherit: m.Inherit,
+		Set:     m.Set,
+	}, err
+}
+
+// PasswordRule holds the source of role password: either a value, a file or a
+// command.
+type PasswordRule struct {
+	Value   pyfmt.Format
+	File    pyfmt.Format
+	Command pyfmt.Format
+}
+
+func (p PasswordRule) Formats() []pyfmt.Format {
+	return []pyfmt.Format{p.Value, p.File, p.Command}
+}
+
+// Generate formats password source from values of a generated role.
+func (p PasswordRule) Generate(values map[string]string) (password role.Password, err error) {
+	var errs [3]error
+	password.Value, errs[0] = p.Value.Render(values)
+	password.File, errs[1] = p.File.Render(values)
+	password.Command, errs[2] = p.Command.Render(values)
+	return password, errors.Join(errs[:]...)
+}
+
+// isTrue interprets a formatted flag. Empty and false-like values are false.
+// Any other value is true, e.g. a lock timestamp.
+func isTrue(v string) bool {
+	switch strings.ToLower(v) {
+	case "", "0", "false", "n", "no", "off":
+		return false
+	default:
+		return true
 	}
 }
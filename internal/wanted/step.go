@@ -7,7 +7,6 @@ import (
 	"github.com/dalibo/ldap2pg/v6/internal/ldap"
 	"github.com/dalibo/ldap2pg/v6/internal/privileges"
 	"github.com/dalibo/ldap2pg/v6/internal/pyfmt"
-	"github.com/dalibo/ldap2pg/v6/internal/role"
 	mapset "github.com/deckarep/golang-set/v2"
 	ldap3 "github.com/go-ldap/ldap/v3"
 	"golang.org/x/exp/slices"
@@ -54,6 +53,12 @@ func (s *Step) InferAttributes() {
 		subsearchAttributes[attribute] = subAttributes
 	}
 
+	for _, rule := range s.RoleRules {
+		if rule.MirrorNesting != "" {
+			attributes.Add(rule.MirrorNesting)
+		}
+	}
+
 	if attributes.Cardinality() == 0 {
 		return
 	}
@@ -86,10 +91,10 @@ func (s *Step) InferAttributes() {
 }
 
 func (s *Step) ReplaceAttributeAsSubentryField() {
-	subsearchAttr := s.LdapSearch.SubsearchAttribute()
+	subsearchAttrs := s.LdapSearch.SubsearchAttributes()
 	for field := range s.IterFields() {
 		attribute, _, found := strings.Cut(field.FieldName, ".")
-		if attribute != subsearchAttr {
+		if !slices.Contains(subsearchAttrs, attribute) {
 			continue
 		}
 		// When sub-searching, never use sub attribute directly but
@@ -172,9 +177,10 @@ type SearchResult struct {
 	err    error
 }
 
-// search directory, returning each entry or error. Sub-searches are done
-// concurrently and returned for each sub-key.
-func (s Step) search(ldapc ldap.Client) <-chan SearchResult {
+// search directory, returning each entry or error. Sub-searches of entries
+// are done concurrently and returned with their parent entry, in search
+// order.
+func (s Step) search(pool *ldap.Pool) <-chan SearchResult {
 	ch := make(chan SearchResult)
 	go func() {
 		defer close(ch)
@@ -185,61 +191,96 @@ func (s Step) search(ldapc ldap.Client) <-chan SearchResult {
 		}
 
 		search := s.LdapSearch
-		res, err := ldapc.Search(search.Base, search.Scope, search.Filter, search.Attributes)
+		res, err := pool.Search(search.Base, search.Scope, search.Filter, search.Attributes, search.PageSize)
 		if err != nil {
 			ch <- SearchResult{err: err}
 			return
 		}
-		subsearchAttr := s.LdapSearch.SubsearchAttribute()
-		for _, entry := range res.Entries {
-			slog.Debug("Got LDAP entry.", "dn", entry.DN)
-			result := ldap.Result{
-				Entry:              entry,
-				SubsearchAttribute: subsearchAttr,
-			}
-			if subsearchAttr == "" {
-				ch <- SearchResult{result: result}
-				continue
+
+		// One slot per entry to emit results in search order.
+		slots := make([]chan []SearchResult, len(res.Entries))
+		for i := range slots {
+			slots[i] = make(chan []SearchResult, 1)
+		}
+		jobs := make(chan int)
+		go func() {
+			defer close(jobs)
+			for i := range res.Entries {
+				jobs <- i
 			}
-			bases := entry.GetEqualFoldAttributeValues(subsearchAttr)
-			for _, base := range bases {
-				s := s.LdapSearch.Subsearches[subsearchAttr]
-				res, err = ldapc.Search(base, s.Scope, s.Filter, s.Attributes)
-				if err != nil {
-					ch <- SearchResult{err: err}
-					continue
+		}()
+		for range min(pool.Size(), len(res.Entries)) {
+			go func() {
+				for i := range jobs {
+					slots[i] <- s.join(pool, res.Entries[i])
 				}
-				// Copy results in scope.
-				result := result
-				// Overwrite previous sub-entries and resend results.
-				result.SubsearchEntries = res.Entries
-				ch <- SearchResult{result: result}
+			}()
+		}
+		for _, slot := range slots {
+			for _, result := range <-slot {
+				ch <- result
 			}
 		}
 	}()
 	return ch
 }
 
-func (s Step) generateRoles(results *ldap.Result) <-chan role.Role {
-	ch := make(chan role.Role)
+// join resolves sub-searches of entry.
+func (s Step) join(pool *ldap.Pool, entry *ldap3.Entry) (out []SearchResult) {
+	slog.Debug("Got LDAP entry.", "dn", entry.DN)
+	result := ldap.Result{
+		Entry:          entry,
+		OnUnexpectedDN: s.LdapSearch.OnUnexpectedDN,
+	}
+	subsearchAttrs := s.LdapSearch.SubsearchAttributes()
+	if len(subsearchAttrs) == 0 {
+		return []SearchResult{{result: result}}
+	}
+	// Resolve each join independently. Generation combines
+	// sub-entries of all joins.
+	result.SubsearchEntries = make(map[string][]*ldap3.Entry)
+	for _, attr := range subsearchAttrs {
+		s := s.LdapSearch.Subsearches[attr]
+		if s.Transitive {
+			subEntries, err := pool.SearchTransitiveMembers(entry, attr, s)
+			if err != nil {
+				out = append(out, SearchResult{err: err})
+			}
+			result.SubsearchEntries[attr] = subEntries
+			continue
+		}
+		subEntries, errs := pool.Subsearch(entry.GetEqualFoldAttributeValues(attr), s)
+		for _, err := range errs {
+			out = append(out, SearchResult{err: err})
+		}
+		result.SubsearchEntries[attr] = subEntries
+	}
+	return append(out, SearchResult{result: result})
+}
+
+func (s Step) generateRoles(results *ldap.Result, nestings nestings) <-chan GeneratedRole {
+	ch := make(chan GeneratedRole)
 	go func() {
 		defer close(ch)
-		for _, rule := range s.RoleRules {
-			for role := range rule.Generate(results) {
-				ch <- role
+		for i, rule := range s.RoleRules {
+			for generated := range rule.Generate(results) {
+				if generated.err == nil && rule.MirrorNesting != "" && results.Entry != nil {
+					nestings.track(results, i, rule.MirrorNesting, generated.role.Name)
+				}
+				ch <- generated
 			}
 		}
 	}()
 	return ch
 }
 
-func (s Step) generateGrants(results *ldap.Result) <-chan privileges.Grant {
-	ch := make(chan privileges.Grant)
+func (s Step) generateGrants(results *ldap.Result) <-chan privileges.GeneratedGrant {
+	ch := make(chan privileges.GeneratedGrant)
 	go func() {
 		defer close(ch)
 		for _, rule := range s.GrantRules {
-			for grant := range rule.Generate(results) {
-				ch <- grant
+			for generated := range rule.Generate(results) {
+				ch <- generated
 			}
 		}
 	}()
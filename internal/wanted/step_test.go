@@ -1,7 +1,14 @@
 package wanted_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/dalibo/ldap2pg/v6/internal/config"
+	"github.com/dalibo/ldap2pg/v6/internal/ldap"
+	"github.com/dalibo/ldap2pg/v6/internal/role"
 	"github.com/lithammer/dedent"
 	"gopkg.in/yaml.v3"
 )
@@ -45,7 +52,7 @@ func (suite *Suite) TestItemLdapAnalyze() {
 	i.InferAttributes()
 	r.True(i.HasLDAPSearch())
 	r.True(i.HasSubsearch())
-	r.Equal("member", i.LdapSearch.SubsearchAttribute())
+	r.Equal([]string{"member"}, i.LdapSearch.SubsearchAttributes())
 }
 
 func (suite *Suite) TestSyncItemReplaceMemberAsMemberDotDN() {
@@ -69,3 +76,512 @@ func (suite *Suite) TestSyncItemReplaceMemberAsMemberDotDN() {
 	}
 	r.Fail("member.dn not found")
 }
+
+func (suite *Suite) TestItemPageSize() {
+	r := suite.Require()
+
+	c := configFromYAML(`
+	rules:
+	- ldapsearch:
+	    base: cn=toto
+	    page_size: 1000
+	    joins:
+	      member:
+	        page_size: 0
+	  roles:
+	  - name: "{member.sAMAccountName}"
+	`)
+	i := c.Rules[0]
+	r.NotNil(i.LdapSearch.PageSize)
+	r.Equal(uint32(1000), *i.LdapSearch.PageSize)
+	r.NotNil(i.LdapSearch.Subsearches["member"].PageSize)
+	r.Equal(uint32(0), *i.LdapSearch.Subsearches["member"].PageSize)
+}
+
+func (suite *Suite) TestItemTransitiveJoin() {
+	r := suite.Require()
+
+	c := configFromYAML(`
+	rules:
+	- ldapsearch:
+	    base: cn=toto
+	    joins:
+	      member:
+	        filter: (objectClass=user)
+	        transitive: true
+	  roles:
+	  - name: "{member.sAMAccountName}"
+	`)
+	i := c.Rules[0]
+	i.InferAttributes()
+	s := i.LdapSearch.Subsearches["member"]
+	r.True(s.Transitive)
+	r.Equal([]string{"sAMAccountName"}, s.Attributes)
+}
+
+func (suite *Suite) TestItemMirrorNesting() {
+	r := suite.Require()
+
+	c := configFromYAML(`
+	rules:
+	- ldapsearch:
+	    base: cn=toto
+	  roles:
+	  - name: "{cn}"
+	    mirror_nesting: member
+	`)
+	i := c.Rules[0]
+	i.InferAttributes()
+	r.Equal("member", i.RoleRules[0].MirrorNesting)
+	r.ElementsMatch([]string{"cn", "member"}, i.LdapSearch.Attributes)
+	r.False(i.HasSubsearch())
+}
+
+func (suite *Suite) TestRunLDIF() {
+	r := suite.Require()
+
+	path := filepath.Join(suite.T().TempDir(), "export.ldif")
+	err := os.WriteFile(path, []byte(dedent.Dedent(`
+	dn: cn=dba,ou=groups,dc=acme,dc=tld
+	cn: dba
+	member: cn=alice,ou=people,dc=acme,dc=tld
+	member: cn=bob,ou=people,dc=acme,dc=tld
+
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	`)), 0o600)
+	r.Nil(err)
+
+	c := configFromYAML(fmt.Sprintf(`
+	rules:
+	- ldapsearch:
+	    ldif: %s
+	    base: cn=dba,ou=groups,dc=acme,dc=tld
+	    scope: base
+	    filter: (objectClass=*)
+	    on_unexpected_dn: fail
+	    joins:
+	      member:
+	        scope: base
+	        filter: (objectClass=*)
+	  roles:
+	  - name: "{member.cn}"
+	    parents:
+	    - name: "{cn}"
+	`, path))
+	i := &c.Rules[0]
+	i.InferAttributes()
+	i.ReplaceAttributeAsSubentryField()
+	roles, _, err := c.Rules.Run(nil, 2)
+	r.Nil(err)
+	r.Len(roles, 2)
+	r.True(roles["alice"].MemberOf("dba"))
+	r.True(roles["bob"].MemberOf("dba"))
+}
+
+func (suite *Suite) TestRunUnexpectedDNPerRule() {
+	r := suite.Require()
+
+	path := filepath.Join(suite.T().TempDir(), "export.ldif")
+	r.Nil(os.WriteFile(path, []byte(dedent.Dedent(`
+	dn: cn=dba,ou=groups,dc=acme,dc=tld
+	cn: dba
+	member: cn=alice,ou=people,dc=acme,dc=tld
+	member: uid=bob,ou=people,dc=acme,dc=tld
+	`)), 0o600))
+
+	c := configFromYAML(fmt.Sprintf(`
+	rules:
+	- ldapsearch:
+	    ldif: %s
+	    base: cn=dba,ou=groups,dc=acme,dc=tld
+	    scope: base
+	    filter: (objectClass=*)
+	    on_unexpected_dn: ignore
+	  roles:
+	  - name: "{member.uid}"
+	  - name: "{member.cn}"
+	`, path))
+	c.Rules[0].InferAttributes()
+	roles, _, err := c.Rules.Run(nil, 1)
+	r.Nil(err)
+	r.Len(roles, 2)
+	r.Contains(roles, "alice")
+	r.Contains(roles, "bob")
+}
+
+// runPeople writes entries of ou=people in an LDIF export and runs a one-level
+// search of ou=people generating roleYAML, a YAML flow mapping.
+func (suite *Suite) runPeople(people, roleYAML string) (role.Map, error) {
+	r := suite.Require()
+
+	path := filepath.Join(suite.T().TempDir(), "export.ldif")
+	ldif := "dn: ou=people,dc=acme,dc=tld\nou: people\n" + dedent.Dedent(people)
+	r.Nil(os.WriteFile(path, []byte(ldif), 0o600))
+
+	c := configFromYAML(fmt.Sprintf(`
+	rules:
+	- ldapsearch:
+	    ldif: %s
+	    base: ou=people,dc=acme,dc=tld
+	    scope: one
+	    filter: (objectClass=*)
+	  roles:
+	  - %s
+	`, path, roleYAML))
+	c.Rules[0].InferAttributes()
+	roles, _, err := c.Rules.Run(nil, 1)
+	return roles, err
+}
+
+func (suite *Suite) TestRunPassword() {
+	r := suite.Require()
+
+	roles, err := suite.runPeople(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	description: secret
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	`, `{name: "{cn}", password: {value: "{description}"}, password_policy: always}`)
+	r.Nil(err)
+	// bob has no password but is still wanted.
+	r.Len(roles, 2)
+	r.Equal("secret", roles["alice"].Password.Value)
+	r.Equal("always", roles["alice"].Password.Policy)
+	r.True(roles["bob"].Password.IsZero())
+}
+
+// runGroup writes cn=dba group with members in an LDIF export and runs a
+// search of cn=dba joining members, generating roleYAML, a YAML flow mapping.
+func (suite *Suite) runGroup(members, roleYAML string) (role.Map, error) {
+	r := suite.Require()
+
+	members = dedent.Dedent(members)
+	ldif := "dn: cn=dba,ou=groups,dc=acme,dc=tld\ncn: dba\n"
+	for _, line := range strings.Split(members, "\n") {
+		if dn, ok := strings.CutPrefix(line, "dn: "); ok {
+			ldif += "member: " + dn + "\n"
+		}
+	}
+	path := filepath.Join(suite.T().TempDir(), "export.ldif")
+	r.Nil(os.WriteFile(path, []byte(ldif+members), 0o600))
+
+	c := configFromYAML(fmt.Sprintf(`
+	rules:
+	- ldapsearch:
+	    ldif: %s
+	    base: cn=dba,ou=groups,dc=acme,dc=tld
+	    scope: base
+	    filter: (objectClass=*)
+	    joins:
+	      member:
+	        scope: base
+	        filter: (objectClass=*)
+	  roles:
+	  - %s
+	`, path, roleYAML))
+	i := &c.Rules[0]
+	i.InferAttributes()
+	i.ReplaceAttributeAsSubentryField()
+	roles, _, err := c.Rules.Run(nil, 1)
+	return roles, err
+}
+
+func (suite *Suite) TestRunPasswordPerMember() {
+	r := suite.Require()
+
+	roles, err := suite.runGroup(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	userPassword: alice-secret
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	userPassword: bob-secret
+
+	dn: cn=carol,ou=people,dc=acme,dc=tld
+	cn: carol
+	`, `{name: "{member.cn}", password: {value: "{member.userPassword}"}}`)
+	r.Nil(err)
+	r.Len(roles, 3)
+	r.Equal("alice-secret", roles["alice"].Password.Value)
+	r.Equal("bob-secret", roles["bob"].Password.Value)
+	r.True(roles["carol"].Password.IsZero())
+}
+
+func (suite *Suite) TestRunValidUntil() {
+	r := suite.Require()
+
+	roles, err := suite.runPeople(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	accountExpires: 134116128000000000
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	`, `{name: "{cn}", valid_until: "{accountExpires.filetime()}"}`)
+	r.Nil(err)
+	r.Len(roles, 2)
+	r.Equal("2025-12-31T00:00:00Z", roles["alice"].ValidUntil)
+	r.Equal("", roles["bob"].ValidUntil)
+}
+
+func (suite *Suite) TestRunValidUntilPerMember() {
+	r := suite.Require()
+
+	roles, err := suite.runGroup(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	shadowExpire: 20453
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	shadowExpire: -1
+
+	dn: cn=carol,ou=people,dc=acme,dc=tld
+	cn: carol
+	shadowExpire: never
+	`, `{name: "{member.cn}", valid_until: "{member.shadowExpire.epochdays()}"}`)
+	r.ErrorContains(err, `epochdays(): "never"`)
+	r.Equal("2025-12-31T00:00:00Z", roles["alice"].ValidUntil)
+	r.Equal("infinity", roles["bob"].ValidUntil)
+	r.NotContains(roles, "carol")
+}
+
+func (suite *Suite) TestRunDisabled() {
+	r := suite.Require()
+
+	roles, err := suite.runPeople(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	userAccountControl: 512
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	userAccountControl: 514
+
+	dn: cn=carol,ou=people,dc=acme,dc=tld
+	cn: carol
+	`, `{name: "{cn}", options: {LOGIN: true}, disabled: "{userAccountControl.flag(2)}"}`)
+	r.Nil(err)
+	r.Len(roles, 3)
+	r.True(roles["alice"].Options.CanLogin)
+	r.False(roles["bob"].Options.CanLogin)
+	r.True(roles["carol"].Options.CanLogin)
+}
+
+func (suite *Suite) TestRunDisabledPerMember() {
+	r := suite.Require()
+
+	roles, err := suite.runGroup(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	userAccountControl: 512
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	userAccountControl: 514
+
+	dn: cn=carol,ou=people,dc=acme,dc=tld
+	cn: carol
+	userAccountControl: x
+	`, `{name: "{member.cn}", options: {LOGIN: true}, disabled: "{member.userAccountControl.flag(2)}"}`)
+	r.ErrorContains(err, `flag(): "x": expected integer`)
+	r.True(roles["alice"].Options.CanLogin)
+	r.False(roles["bob"].Options.CanLogin)
+	r.NotContains(roles, "carol")
+}
+
+func (suite *Suite) TestRunKey() {
+	r := suite.Require()
+
+	roles, err := suite.runPeople(`
+	dn: cn=jsmith,ou=people,dc=acme,dc=tld
+	cn: jsmith
+	entryUUID: 6f2a9c3e-1b1d-4c1e-9a53-0e1f2d3c4b5a
+	`, `{name: "{cn}", key: "{entryUUID}"}`)
+	r.Nil(err)
+	r.Equal("6f2a9c3e-1b1d-4c1e-9a53-0e1f2d3c4b5a", roles["jsmith"].Key)
+}
+
+func (suite *Suite) TestRunKeyPerMember() {
+	r := suite.Require()
+
+	roles, err := suite.runGroup(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	entryUUID: 6f2a9c3e-1b1d-4c1e-9a53-0e1f2d3c4b5a
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	entryUUID: 0c9d8e7f-6a5b-4c3d-2e1f-0a9b8c7d6e5f
+
+	dn: cn=carol,ou=people,dc=acme,dc=tld
+	cn: carol
+	`, `{name: "{member.cn}", key: "{member.entryUUID}"}`)
+	r.Nil(err)
+	r.Len(roles, 3)
+	r.Equal("6f2a9c3e-1b1d-4c1e-9a53-0e1f2d3c4b5a", roles["alice"].Key)
+	r.Equal("0c9d8e7f-6a5b-4c3d-2e1f-0a9b8c7d6e5f", roles["bob"].Key)
+	r.Equal("", roles["carol"].Key)
+}
+
+func (suite *Suite) TestRunInvalidGUID() {
+	r := suite.Require()
+
+	roles, err := suite.runPeople(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	objectGUID: short
+
+	dn: cn=bob,ou=people,dc=acme,dc=tld
+	cn: bob
+	objectGUID:: T5orEDxefUqLHA0uP0BRYg==
+	`, `{name: "{cn}", comment: "objectGUID: {objectGUID.guid()}"}`)
+	r.ErrorContains(err, "cn=alice,ou=people,dc=acme,dc=tld: objectGUID: guid(): 5 bytes, expected 16")
+	r.NotContains(roles, "alice")
+	r.Equal("objectGUID: 102b9a4f-5e3c-4a7d-8b1c-0d2e3f405162", roles["bob"].Comment)
+}
+
+func (suite *Suite) TestRunGrantError() {
+	r := suite.Require()
+
+	path := filepath.Join(suite.T().TempDir(), "export.ldif")
+	r.Nil(os.WriteFile(path, []byte(dedent.Dedent(`
+	dn: cn=alice,ou=people,dc=acme,dc=tld
+	cn: alice
+	objectGUID: short
+	`)), 0o600))
+	c := configFromYAML(fmt.Sprintf(`
+	rules:
+	- ldapsearch:
+	    ldif: %s
+	    base: cn=alice,ou=people,dc=acme,dc=tld
+	    scope: base
+	    filter: (objectClass=*)
+	  roles:
+	  - name: "{cn}"
+	  grants:
+	  - privilege: "{objectGUID.guid()}"
+	    role: "{cn}"
+	`, path))
+	c.Rules[0].InferAttributes()
+	_, _, err := c.Rules.Run(nil, 1)
+	r.ErrorContains(err, "cn=alice,ou=people,dc=acme,dc=tld: objectGUID: guid(): 5 bytes, expected 16")
+}
+
+func (suite *Suite) TestMembershipOptions() {
+	r := suite.Require()
+
+	c := configFromYAML(`
+	rules:
+	- roles:
+	  - name: alice
+	    parents:
+	    - name: app_owner
+	      inherit: "false"
+	      set: true
+	    - name: readers
+	`)
+	roles, _, err := c.Rules.Run(nil, 1)
+	r.Nil(err)
+	parents := roles["alice"].Parents
+	r.Len(parents, 2)
+	r.Nil(parents[0].Admin)
+	r.False(*parents[0].Inherit)
+	r.True(*parents[0].Set)
+	r.False(parents[1].HasOptions())
+}
+
+func (suite *Suite) TestRunConcurrentOrder() {
+	r := suite.Require()
+
+	path := filepath.Join(suite.T().TempDir(), "export.ldif")
+	var ldif strings.Builder
+	for i := range 20 {
+		fmt.Fprintf(&ldif, "dn: cn=g%02d,dc=acme\ncn: g%02d\nmember: cn=alice,dc=acme\n\n", i, i)
+	}
+	ldif.WriteString("dn: cn=alice,dc=acme\ncn: alice\n")
+	r.Nil(os.WriteFile(path, []byte(ldif.String()), 0o600))
+
+	var rules strings.Builder
+	rules.WriteString("rules:\n")
+	for i := range 20 {
+		fmt.Fprintf(&rules, dedent.Dedent(`
+		- ldapsearch:
+		    ldif: %s
+		    base: cn=g%02d,dc=acme
+		    scope: base
+		    filter: (objectClass=*)
+		    on_unexpected_dn: fail
+		    joins:
+		      member:
+		        scope: base
+		        filter: (objectClass=*)
+		  roles:
+		  - name: "{member.cn}"
+		    parents:
+		    - name: "{cn}"
+		`), path, i)
+	}
+	c := configFromYAML(rules.String())
+	for i := range c.Rules {
+		c.Rules[i].InferAttributes()
+		c.Rules[i].ReplaceAttributeAsSubentryField()
+	}
+
+	serial, _, err := c.Rules.Run(nil, 1)
+	r.Nil(err)
+	r.Len(serial["alice"].Parents, 20)
+	concurrent, _, err := c.Rules.Run(nil, 8)
+	r.Nil(err)
+	r.Equal(serial, concurrent)
+}
+
+func (suite *Suite) TestRunNamedDirectory() {
+	r := suite.Require()
+
+	path := filepath.Join(suite.T().TempDir(), "contractors.ldif")
+	r.Nil(os.WriteFile(path, []byte("dn: ou=contractors,dc=acme\nou: contractors\n\ndn: cn=carol,ou=contractors,dc=acme\ncn: carol\n"), 0o600))
+	suite.T().Setenv("LDAPNOINIT", "1")
+	r.Nil(ldap.Initialize(ldap.Config{Directories: map[string]map[string]any{
+		"contractors": {"uri": "file://" + path},
+	}}))
+	// Forget contractors directory before LDAPNOINIT is restored.
+	suite.T().Cleanup(func() { _ = ldap.Initialize(ldap.Config{}) })
+
+	c := configFromYAML(`
+	rules:
+	- ldapsearch:
+	    directory: contractors
+	    base: ou=contractors,dc=acme
+	    filter: (objectClass=*)
+	    scope: one
+	    on_unexpected_dn: fail
+	  roles:
+	  - name: "{cn}"
+	- ldapsearch:
+	    directory: unknown
+	    base: ou=people,dc=acme
+	    filter: (objectClass=*)
+	    scope: one
+	    on_unexpected_dn: fail
+	  roles:
+	  - name: "{cn}"
+	`)
+	for i := range c.Rules {
+		c.Rules[i].InferAttributes()
+	}
+
+	roles, _, err := c.Rules[:1].Run(nil, 1)
+	r.Nil(err)
+	r.Contains(roles, "carol")
+
+	_, _, err = c.Rules.Run(nil, 1)
+	r.ErrorContains(err, "unknown: unknown LDAP directory")
+}